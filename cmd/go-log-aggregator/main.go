@@ -4,49 +4,95 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/checkpoint"
 	"go-log-aggregator/internal/config"
 	"go-log-aggregator/internal/filter"
 	"go-log-aggregator/internal/ingest"
+	"go-log-aggregator/internal/notify"
+	"go-log-aggregator/internal/output"
 	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/silence"
+	"go-log-aggregator/internal/sink"
 	"go-log-aggregator/internal/web"
 )
 
+const (
+	checkpointFlushInterval = 5 * time.Second
+	multilineFlushInterval  = 250 * time.Millisecond
+	storeMaxAge             = 7 * 24 * time.Hour
+	alertTickInterval       = time.Second
+	alertHistorySize        = 1000
+)
+
 func main() {
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "silence":
+			run = runSilence
+		case "validate":
+			run = runValidate
+		case "test-parse":
+			run = runTestParse
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			return
+		}
+	}
+
 	var configPath string
 	var regexFilter string
 	var severityFilter string
 	var sinceFilter string
 	var untilFilter string
 	var fieldFilters multiValue
+	var queryFilter string
 	var httpAddr string
 	var backfill bool
 	var backfillLines int
+	var outputMode string
+	var outputTemplate string
+	var watchConfig bool
 	flag.StringVar(&configPath, "config", "config/config.json", "path to config file")
 	flag.StringVar(&regexFilter, "regex", "", "regex filter applied to raw/message")
 	flag.StringVar(&severityFilter, "severity", "", "severity filter (info, warn, error, critical)")
 	flag.StringVar(&sinceFilter, "since", "", "only include logs since RFC3339 timestamp")
 	flag.StringVar(&untilFilter, "until", "", "only include logs until RFC3339 timestamp")
 	flag.Var(&fieldFilters, "field", "field filter key=value (repeatable)")
+	flag.StringVar(&queryFilter, "query", "", "query filter, e.g. 'source:nginx AND status>=500'")
 	flag.StringVar(&httpAddr, "http-addr", ":8080", "http dashboard address (empty to disable)")
 	flag.BoolVar(&backfill, "backfill", true, "read existing log content on startup")
 	flag.IntVar(&backfillLines, "backfill-lines", 5000, "max lines per source to backfill (0 = no limit)")
+	flag.StringVar(&outputMode, "output", "json", "stdout format: json, logfmt, text, template or none")
+	flag.StringVar(&outputTemplate, "output-template", "", "Go template for -output template, e.g. '{{.SourceName}} {{.Message}}'")
+	flag.BoolVar(&watchConfig, "watch-config", false, "reload the config when the file changes (SIGHUP always reloads)")
 	flag.Parse()
 
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		log.Fatalf("load config: %v", err)
 	}
+	logConfigWarnings(cfg)
 
 	if len(cfg.Sources) == 0 {
 		fmt.Fprintln(os.Stdout, "no sources configured")
@@ -55,10 +101,26 @@ func main() {
 
 	fmt.Fprintln(os.Stdout, "configured sources:")
 	for _, src := range cfg.Sources {
-		fmt.Fprintf(os.Stdout, "- %s (%s) format=%s\n", src.Name, src.Path, src.Format)
+		location := src.Path
+		switch src.Kind() {
+		case config.SourceTypeSyslog:
+			location = src.Address
+		case config.SourceTypeHTTP:
+			location = "POST /api/ingest"
+		}
+		fmt.Fprintf(os.Stdout, "- %s (%s) format=%s\n", src.Name, location, src.Format)
+	}
+
+	sourceWidth := 0
+	for _, src := range cfg.Sources {
+		sourceWidth = max(sourceWidth, len(src.Name))
+	}
+	stdout, err := output.New(outputMode, output.Options{Template: outputTemplate, Color: colorOutput(), SourceWidth: sourceWidth})
+	if err != nil {
+		log.Fatalf("output: %v", err)
 	}
 
-	criteria, err := buildCriteria(regexFilter, severityFilter, sinceFilter, untilFilter, fieldFilters)
+	criteria, err := buildCriteria(regexFilter, severityFilter, sinceFilter, untilFilter, queryFilter, fieldFilters)
 	if err != nil {
 		log.Fatalf("filters: %v", err)
 	}
@@ -68,83 +130,325 @@ func main() {
 		log.Fatalf("alerts: %v", err)
 	}
 
+	notifier, err := notify.NewDispatcher(cfg.Alerts)
+	if err != nil {
+		log.Fatalf("notify: %v", err)
+	}
+
+	sinks, err := sink.NewManager(cfg.Sinks)
+	if err != nil {
+		log.Fatalf("sinks: %v", err)
+	}
+	sinks.Start()
+	defer func() {
+		if err := sinks.Close(); err != nil {
+			log.Printf("close sinks: %v", err)
+		}
+	}()
+
+	silences, err := silence.Open(cfg.SilencesPath)
+	if err != nil {
+		log.Fatalf("silences: %v", err)
+	}
+	defer func() {
+		if err := silences.Flush(); err != nil {
+			log.Printf("flush silences: %v", err)
+		}
+	}()
+
+	parser, err := parse.NewParser(cfg)
+	if err != nil {
+		log.Fatalf("formats: %v", err)
+	}
+
+	assembler, err := ingest.NewAssembler(cfg.Sources)
+	if err != nil {
+		log.Fatalf("multiline: %v", err)
+	}
+
+	// Without checkpointPath offsets are kept in memory, so backfill can
+	// still hand each file over to its tailer.
+	checkpoints, err := checkpoint.Open(cfg.CheckpointPath)
+	if err != nil {
+		log.Fatalf("checkpoints: %v", err)
+	}
+	defer func() {
+		if err := checkpoints.Flush(); err != nil {
+			log.Printf("flush checkpoints: %v", err)
+		}
+	}()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	events := make(chan ingest.Event, 128)
+	errs := make(chan error, 16)
+
+	// The main loop owns cfg; HTTP handlers read the current sources here.
+	var current atomic.Pointer[config.Config]
+	current.Store(&cfg)
+	currentSources := func() []config.Source { return current.Load().Sources }
+
 	var hub *web.Hub
-	var store *web.Store
+	var store web.EventStore
+	var alertHistory *web.AlertHistory
 	if httpAddr != "" {
 		hub = web.NewHub()
-		store = web.NewStore(7*24*time.Hour, 50000)
+		alertHistory = web.NewAlertHistory(alertHistorySize)
+		store, err = openStore(cfg.Store)
+		if err != nil {
+			log.Fatalf("store: %v", err)
+		}
+		if closer, ok := store.(io.Closer); ok {
+			defer closer.Close()
+		}
 		go hub.Run(ctx)
 		go func() {
-			if err := web.StartServer(ctx, httpAddr, hub, store, sourceNames(cfg.Sources)); err != nil {
+			if err := web.StartServer(ctx, httpAddr, hub, store, func() []string { return sourceNames(currentSources()) }, httpIngest(currentSources, events), silences, alertHistory); err != nil {
 				log.Printf("http server: %v", err)
 			}
 		}()
 	}
-
-	events := make(chan ingest.Event, 128)
-	errs := make(chan error, 16)
+	go checkpoints.Run(ctx, checkpointFlushInterval, errs)
+	go silences.Run(ctx, checkpointFlushInterval, errs)
+	// Alerts still queued at shutdown are sent before exiting.
+	notifyDone := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(notifyDone)
+	}()
+	defer func() { <-notifyDone }()
+
+	// Silenced alerts are still evaluated and counted, just not sent.
+	handleAlert := func(match alert.Match) {
+		now := time.Now()
+		id, muted := silences.Muted(match, now)
+		record := alertHistory.Record(match, id, now)
+		if data, err := json.Marshal(record); err == nil {
+			hub.Publish("alert", data)
+		}
+		if muted {
+			log.Printf("SILENCED %s group=%q by %s", match.RuleName, match.Group, id)
+			return
+		}
+		logAlert(match)
+		notifier.Send(match)
+	}
 
 	handleEvent := func(event ingest.Event) {
 		if strings.TrimSpace(event.Line) == "" {
 			return
 		}
-		parsed, err := parse.ParseLine(sourceFormat(cfg.Sources, event.SourceName), event)
+		parsed, err := parser.Parse(sourceFormat(cfg.Sources, event.SourceName), event)
 		if err != nil {
-			parsed = parse.StructuredEvent{
-				SourceName: event.SourceName,
-				SourcePath: event.SourcePath,
-				Format:     "unknown",
-				ReceivedAt: event.ReceivedAt,
-				Severity:   "unknown",
-				Message:    event.Line,
-				Raw:        event.Line,
-			}
+			parsed = parse.Unparsed(event)
 		}
 
 		if !criteria.Matches(parsed) {
 			return
 		}
 
-		webEvent, payload := toWebEvent(parsed)
+		sinks.Send(parsed)
+		printEvent(stdout, parsed)
 		if store != nil {
-			store.Add(webEvent)
+			store.Add(web.NewEvent(parsed))
 		}
-		if payload != nil && hub != nil {
-			hub.Broadcast(payload)
+		if hub != nil {
+			// The dashboard always gets JSON, whatever -output is.
+			if payload, err := (output.JSON{}).Format(parsed); err == nil {
+				hub.Broadcast(payload)
+			}
 		}
 		for _, match := range alerts.Evaluate(parsed) {
-			log.Printf("ALERT %s source=%s message=%s", match.RuleName, match.Event.SourceName, match.Event.Message)
+			handleAlert(match)
+		}
+	}
+
+	handleAssembled := func(event ingest.Event) {
+		for _, assembled := range assembler.Add(event) {
+			handleEvent(assembled)
 		}
 	}
 
 	if backfill {
 		for _, src := range cfg.Sources {
-			if err := backfillSource(src, backfillLines, handleEvent); err != nil {
+			if src.Kind() != config.SourceTypeFile {
+				continue
+			}
+			if err := backfillSource(src, backfillLines, checkpoints, handleAssembled); err != nil {
 				log.Printf("backfill %s: %v", src.Name, err)
 			}
 		}
+		for _, assembled := range assembler.FlushAll() {
+			handleEvent(assembled)
+		}
 	}
 
+	running := make(map[string]context.CancelFunc)
+	start := func(src config.Source) {
+		cancel, err := startSource(ctx, src, checkpoints, events, errs)
+		if err != nil {
+			log.Printf("start source %s: %v", src.Name, err)
+			return
+		}
+		running[src.Name] = cancel
+	}
 	for _, src := range cfg.Sources {
-		if err := ingest.StartTailer(ctx, src, events, errs); err != nil {
-			log.Printf("start tailer %s: %v", src.Name, err)
+		start(src)
+	}
+	// Absence rules start counting silence only once backfill is done.
+	alerts.Start(time.Now())
+
+	handleTailed := func(events []ingest.Event) {
+		for _, event := range events {
+			handleEvent(event)
+			checkpoints.Commit(event.SourcePath, event.FileID, event.Offset)
+		}
+	}
+
+	// rewatch follows the files the config is made of, which change as
+	// includes are added or removed. The old watch stops once the new one
+	// is running.
+	configChanged := make(chan struct{}, 1)
+	var watching []string
+	stopWatch := func() {}
+	rewatch := func(files []string) {
+		if !watchConfig || slices.Equal(files, watching) {
+			return
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		if err := config.Watch(watchCtx, files, configChanged, errs); err != nil {
+			cancel()
+			log.Printf("watch config: %v", err)
+			return
 		}
+		stopWatch()
+		watching, stopWatch = files, cancel
 	}
 
-	log.Println("tailing configured sources (ctrl+c to stop)")
+	// applyConfig builds everything the new config needs before touching
+	// the running pipeline, so an invalid config changes nothing.
+	applyConfig := func() error {
+		next, err := config.Load(configPath)
+		// Watch a new include even when it is broken, so fixing it reloads.
+		rewatch(next.Files)
+		if err != nil {
+			return err
+		}
+		logConfigWarnings(next)
+		nextParser, err := parse.NewParser(next)
+		if err != nil {
+			return fmt.Errorf("formats: %w", err)
+		}
+		nextAssembler, err := ingest.NewAssembler(next.Sources)
+		if err != nil {
+			return fmt.Errorf("multiline: %w", err)
+		}
+		now := time.Now()
+		nextAlerts, resolved, err := alerts.Reload(next.Alerts, now)
+		if err != nil {
+			return fmt.Errorf("alerts: %w", err)
+		}
+		nextSinks := sinks
+		if !reflect.DeepEqual(cfg.Sinks, next.Sinks) {
+			if nextSinks, err = sink.NewManager(next.Sinks); err != nil {
+				return fmt.Errorf("sinks: %w", err)
+			}
+		}
+		if err := notifier.Reload(next.Alerts); err != nil {
+			if nextSinks != sinks {
+				_ = nextSinks.Close()
+			}
+			return fmt.Errorf("notify: %w", err)
+		}
+
+		for _, key := range restartRequired(cfg, next) {
+			log.Printf("reload: %s changed, restart to apply it", key)
+		}
+		handleTailed(assembler.FlushAll())
+		diff := config.DiffSources(cfg.Sources, next.Sources)
+		for _, src := range append(diff.Removed, diff.Changed...) {
+			if cancel, ok := running[src.Name]; ok {
+				cancel()
+				delete(running, src.Name)
+			}
+		}
+
+		cfg = next
+		current.Store(&next)
+		parser, assembler, alerts = nextParser, nextAssembler, nextAlerts
+		if nextSinks != sinks {
+			previous := sinks
+			sinks = nextSinks
+			sinks.Start()
+			go func() {
+				if err := previous.Close(); err != nil {
+					log.Printf("close sinks: %v", err)
+				}
+			}()
+		}
+		for _, match := range resolved {
+			handleAlert(match)
+		}
+
+		if backfill {
+			for _, src := range diff.Added {
+				if src.Kind() != config.SourceTypeFile {
+					continue
+				}
+				if err := backfillSource(src, backfillLines, checkpoints, handleAssembled); err != nil {
+					log.Printf("backfill %s: %v", src.Name, err)
+				}
+			}
+			for _, assembled := range assembler.FlushAll() {
+				handleEvent(assembled)
+			}
+		}
+		for _, src := range append(diff.Added, diff.Changed...) {
+			start(src)
+		}
+		log.Printf("config reloaded: %d sources (%d added, %d removed, %d changed), %d alert rules",
+			len(next.Sources), len(diff.Added), len(diff.Removed), len(diff.Changed), len(next.Alerts))
+		return nil
+	}
+	reload := func(reason string) {
+		log.Printf("reloading config (%s)", reason)
+		if err := applyConfig(); err != nil {
+			log.Printf("reload: keeping current config: %v", err)
+		}
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	rewatch(cfg.Files)
+
+	flushTicker := time.NewTicker(multilineFlushInterval)
+	defer flushTicker.Stop()
+	alertTicker := time.NewTicker(alertTickInterval)
+	defer alertTicker.Stop()
+
+	log.Println("reading configured sources (ctrl+c to stop)")
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case err := <-errs:
 			if err != nil {
-				log.Printf("tailer error: %v", err)
+				log.Printf("source error: %v", err)
 			}
+		case <-hup:
+			reload("SIGHUP")
+		case <-configChanged:
+			reload("config file changed")
 		case event := <-events:
-			handleEvent(event)
+			handleTailed(assembler.Add(event))
+		case now := <-flushTicker.C:
+			handleTailed(assembler.Expire(now))
+		case now := <-alertTicker.C:
+			for _, match := range alerts.Tick(now) {
+				handleAlert(match)
+			}
 		}
 	}
 }
@@ -163,7 +467,7 @@ func (m *multiValue) Set(value string) error {
 	return nil
 }
 
-func buildCriteria(regexFilter, severityFilter, sinceFilter, untilFilter string, fieldFilters []string) (filter.Criteria, error) {
+func buildCriteria(regexFilter, severityFilter, sinceFilter, untilFilter, queryFilter string, fieldFilters []string) (filter.Criteria, error) {
 	var criteria filter.Criteria
 
 	if strings.TrimSpace(regexFilter) != "" {
@@ -202,9 +506,41 @@ func buildCriteria(regexFilter, severityFilter, sinceFilter, untilFilter string,
 		criteria.Fields = fields
 	}
 
+	if strings.TrimSpace(queryFilter) != "" {
+		query, err := filter.ParseQuery(queryFilter)
+		if err != nil {
+			return filter.Criteria{}, err
+		}
+		criteria.Query = query
+	}
+
 	return criteria, nil
 }
 
+// openStore returns the disk-backed store when one is configured and the
+// in-memory one otherwise.
+func openStore(cfg *config.Store) (web.EventStore, error) {
+	if cfg == nil {
+		return web.NewStore(storeMaxAge, 50000), nil
+	}
+
+	maxAge := storeMaxAge
+	if cfg.MaxAge != "" {
+		dur, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil {
+			return nil, err
+		}
+		maxAge = dur
+	}
+	return web.OpenDiskStore(cfg.Path, maxAge, cfg.MaxBytes)
+}
+
+func logConfigWarnings(cfg config.Config) {
+	for _, warning := range cfg.Warnings {
+		log.Printf("config: %s (ignored)", warning)
+	}
+}
+
 func sourceFormat(sources []config.Source, name string) string {
 	for _, src := range sources {
 		if src.Name == name {
@@ -214,55 +550,74 @@ func sourceFormat(sources []config.Source, name string) string {
 	return ""
 }
 
-type outputEvent struct {
-	Timestamp  string            `json:"timestamp,omitempty"`
-	ReceivedAt string            `json:"received_at,omitempty"`
-	Severity   string            `json:"severity,omitempty"`
-	Message    string            `json:"message,omitempty"`
-	Source     string            `json:"source"`
-	Format     string            `json:"format,omitempty"`
-	Fields     map[string]string `json:"fields,omitempty"`
-	Raw        string            `json:"raw,omitempty"`
+// printEvent writes event to stdout with formatter; nil prints nothing.
+func printEvent(formatter output.Formatter, event parse.StructuredEvent) {
+	if formatter == nil {
+		return
+	}
+	line, err := formatter.Format(event)
+	if err != nil {
+		log.Printf("format output: %v", err)
+		return
+	}
+	_, _ = os.Stdout.Write(append(line, '\n'))
 }
 
-func toWebEvent(event parse.StructuredEvent) (web.Event, []byte) {
-	eventTime := event.Timestamp
-	if eventTime.IsZero() {
-		eventTime = event.ReceivedAt
+// colorOutput reports whether stdout is a terminal that wants colors.
+func colorOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-	webEvent := web.Event{
-		Timestamp:  eventTime,
-		ReceivedAt: event.ReceivedAt,
-		Severity:   event.Severity,
-		Message:    event.Message,
-		Source:     event.SourceName,
-		Format:     event.Format,
-		Fields:     event.Fields,
-		Raw:        event.Raw,
+func logAlert(match alert.Match) {
+	switch match.State {
+	case alert.StateFiring:
+		log.Printf("ALERT %s firing group=%q value=%g threshold=%g window=%s", match.RuleName, match.Group, match.Value, match.Threshold, match.Window)
+	case alert.StateResolved:
+		log.Printf("RESOLVED %s group=%q value=%g threshold=%g window=%s", match.RuleName, match.Group, match.Value, match.Threshold, match.Window)
+	default:
+		if match.Count > 1 || match.Suppressed > 0 {
+			log.Printf("ALERT %s source=%s message=%s count=%d suppressed=%d", match.RuleName, match.Event.SourceName, match.Event.Message, match.Count, match.Suppressed)
+			return
+		}
+		log.Printf("ALERT %s source=%s message=%s", match.RuleName, match.Event.SourceName, match.Event.Message)
 	}
+}
 
-	out := outputEvent{
-		Severity:   webEvent.Severity,
-		Message:    webEvent.Message,
-		Source:     webEvent.Source,
-		Format:     webEvent.Format,
-		Fields:     webEvent.Fields,
-		Raw:        webEvent.Raw,
-		ReceivedAt: webEvent.ReceivedAt.Format(time.RFC3339),
-	}
-	if !webEvent.Timestamp.IsZero() {
-		out.Timestamp = webEvent.Timestamp.Format(time.RFC3339)
-	}
+// httpIngest queues lines pushed over HTTP on the shared event channel, so
+// they take the same parse/filter/alert/store path as tailed lines.
+func httpIngest(sources func() []config.Source, events chan<- ingest.Event) web.IngestFunc {
+	return func(ctx context.Context, source, peer string, lines []string) error {
+		accepted := false
+		for _, src := range sources() {
+			if src.Name == source && src.Kind() == config.SourceTypeHTTP {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return fmt.Errorf("%w: %s", web.ErrUnknownSource, source)
+		}
 
-	data, err := json.Marshal(out)
-	if err != nil {
-		log.Printf("marshal output: %v", err)
-		return webEvent, nil
+		now := time.Now()
+		for _, line := range lines {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case events <- ingest.Event{
+				SourceName: source,
+				SourcePath: "http://" + peer,
+				Line:       line,
+				Peer:       peer,
+				ReceivedAt: now,
+			}:
+			}
+		}
+		return nil
 	}
-
-	fmt.Fprintln(os.Stdout, string(data))
-	return webEvent, data
 }
 
 func sourceNames(sources []config.Source) []string {
@@ -273,32 +628,60 @@ func sourceNames(sources []config.Source) []string {
 	return out
 }
 
-func backfillSource(source config.Source, limit int, handle func(ingest.Event)) error {
-	file, err := os.Open(source.Path)
+func backfillSource(source config.Source, limit int, checkpoints *checkpoint.Store, handle func(ingest.Event)) error {
+	paths, err := ingest.ExpandPaths(source.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		// Checkpointed files are resumed by the tailer instead.
+		if checkpoints.Has(path) {
+			continue
+		}
+		if err := backfillFile(source, path, limit, checkpoints, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillFile handles up to limit complete lines from the start of path
+// and checkpoints where it stopped, so the tailer reads on from there
+// instead of from the end of the file.
+func backfillFile(source config.Source, path string, limit int, checkpoints *checkpoint.Store, handle func(ingest.Event)) error {
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 2*1024*1024)
+	id, err := checkpoint.Identify(file)
+	if err != nil {
+		return err
+	}
 
-	count := 0
-	for scanner.Scan() {
+	reader := bufio.NewReaderSize(file, 64*1024)
+	var offset int64
+	for count := 0; limit <= 0 || count < limit; count++ {
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			// An unterminated last line is left for the tailer.
+			break
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(line))
 		handle(ingest.Event{
 			SourceName: source.Name,
-			SourcePath: source.Path,
-			Line:       scanner.Text(),
+			SourcePath: path,
+			Line:       strings.TrimRight(line, "\r\n"),
+			Offset:     offset,
+			FileID:     id,
 			ReceivedAt: time.Now(),
 		})
-		count++
-		if limit > 0 && count >= limit {
-			break
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return err
 	}
+	checkpoints.Track(path, id, offset)
 	return nil
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"syscall"
+	"time"
+
+	"go-log-aggregator/internal/checkpoint"
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/ingest"
+)
+
+// listenRetries covers a restarted syslog source whose old listener is
+// still closing when the new one binds the same address.
+const (
+	listenRetries    = 5
+	listenRetryDelay = 100 * time.Millisecond
+)
+
+// startSource starts src under its own context; cancel stops it alone.
+func startSource(ctx context.Context, src config.Source, checkpoints *checkpoint.Store, events chan<- ingest.Event, errs chan<- error) (context.CancelFunc, error) {
+	for attempt := 0; ; attempt++ {
+		srcCtx, cancel := context.WithCancel(ctx)
+		err := ingest.StartSource(srcCtx, src, checkpoints, events, errs)
+		if err == nil {
+			return cancel, nil
+		}
+		cancel()
+		if attempt >= listenRetries || !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, err
+		}
+		time.Sleep(listenRetryDelay)
+	}
+}
+
+// restartRequired lists the settings that differ between old and next but
+// are only read at startup.
+func restartRequired(old, next config.Config) []string {
+	var keys []string
+	if old.CheckpointPath != next.CheckpointPath {
+		keys = append(keys, "checkpointPath")
+	}
+	if !reflect.DeepEqual(old.Store, next.Store) {
+		keys = append(keys, "store")
+	}
+	if old.SilencesPath != next.SilencesPath {
+		keys = append(keys, "silencesPath")
+	}
+	return keys
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-log-aggregator/internal/silence"
+)
+
+const silenceUsage = `usage: go-log-aggregator silence <command> [flags]
+
+commands:
+  list                  show silences
+  add                   mute matching alerts, e.g.
+                        silence add -rule nginx-5xx-rate -match host=web-1 -duration 2h -comment deploy
+  expire <id>...        end silences early`
+
+// runSilence manages silences on a running aggregator through its HTTP API.
+func runSilence(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", silenceUsage)
+	}
+
+	fs := flag.NewFlagSet("silence "+args[0], flag.ContinueOnError)
+	var baseURL string
+	fs.StringVar(&baseURL, "url", "http://localhost:8080", "aggregator address")
+
+	switch args[0] {
+	case "list":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		var silences []silence.Silence
+		if err := silenceRequest(http.MethodGet, baseURL, "", nil, &silences); err != nil {
+			return err
+		}
+		printSilences(os.Stdout, silences)
+		return nil
+
+	case "add":
+		var rule, source, start, end, author, comment string
+		var duration time.Duration
+		var matches multiValue
+		fs.StringVar(&rule, "rule", "", "alert rule name")
+		fs.StringVar(&source, "source", "", "source name")
+		fs.Var(&matches, "match", "field matcher key=value, or key=~regex (repeatable)")
+		fs.DurationVar(&duration, "duration", time.Hour, "how long the silence lasts")
+		fs.StringVar(&start, "start", "", "start time, RFC3339 (default now)")
+		fs.StringVar(&end, "end", "", "end time, RFC3339 (overrides -duration)")
+		fs.StringVar(&author, "author", os.Getenv("USER"), "who created the silence")
+		fs.StringVar(&comment, "comment", "", "why the alerts are muted")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		req, err := buildSilence(rule, source, start, end, duration, matches)
+		if err != nil {
+			return err
+		}
+		req.CreatedBy = author
+		req.Comment = comment
+
+		var created silence.Silence
+		if err := silenceRequest(http.MethodPost, baseURL, "", req, &created); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, created.ID)
+		return nil
+
+	case "expire":
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			return fmt.Errorf("silence id is required")
+		}
+		for _, id := range fs.Args() {
+			if err := silenceRequest(http.MethodDelete, baseURL, id, nil, nil); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], silenceUsage)
+	}
+}
+
+func buildSilence(rule, source, start, end string, duration time.Duration, matches []string) (silence.Silence, error) {
+	var req silence.Silence
+	if rule != "" {
+		req.Matchers = append(req.Matchers, silence.Matcher{Name: "rule", Value: rule})
+	}
+	if source != "" {
+		req.Matchers = append(req.Matchers, silence.Matcher{Name: "source", Value: source})
+	}
+	for _, match := range matches {
+		key, value, ok := strings.Cut(match, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return req, fmt.Errorf("invalid matcher %q (use key=value or key=~regex)", match)
+		}
+		matcher := silence.Matcher{Name: strings.TrimSpace(key), Value: value}
+		if strings.HasPrefix(value, "~") {
+			matcher.Value = value[1:]
+			matcher.Regex = true
+		}
+		req.Matchers = append(req.Matchers, matcher)
+	}
+	if len(req.Matchers) == 0 {
+		return req, fmt.Errorf("at least one of -rule, -source or -match is required")
+	}
+
+	req.StartsAt = time.Now()
+	if start != "" {
+		ts, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return req, fmt.Errorf("invalid -start: %w", err)
+		}
+		req.StartsAt = ts
+	}
+	req.EndsAt = req.StartsAt.Add(duration)
+	if end != "" {
+		ts, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return req, fmt.Errorf("invalid -end: %w", err)
+		}
+		req.EndsAt = ts
+	}
+	return req, nil
+}
+
+func silenceRequest(method, baseURL, id string, body, out interface{}) error {
+	endpoint := strings.TrimRight(baseURL, "/") + "/api/silences"
+	if id != "" {
+		endpoint += "/" + url.PathEscape(id)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func printSilences(w io.Writer, silences []silence.Silence) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATE\tSTARTS\tENDS\tMATCHERS\tSUPPRESSED\tCREATED BY\tCOMMENT")
+	for _, s := range silences {
+		matchers := make([]string, 0, len(s.Matchers))
+		for _, m := range s.Matchers {
+			op := "="
+			if m.Regex {
+				op = "=~"
+			}
+			matchers = append(matchers, m.Name+op+m.Value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			s.ID, s.State,
+			s.StartsAt.Local().Format(time.RFC3339), s.EndsAt.Local().Format(time.RFC3339),
+			strings.Join(matchers, ","), s.Suppressed, s.CreatedBy, s.Comment)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/ingest"
+	"go-log-aggregator/internal/notify"
+	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/sink"
+)
+
+// runValidate loads a config and builds everything the aggregator would,
+// listing every problem instead of stopping at the first.
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	var configPath string
+	fs.StringVar(&configPath, "config", "config/config.json", "path to config file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		configPath = fs.Arg(0)
+	}
+
+	// An invalid config still comes back parsed, so its components are
+	// checked too. Unknown keys only warn when running, but count here.
+	cfg, err := config.Load(configPath)
+	for _, warning := range cfg.Warnings {
+		err = errors.Join(err, errors.New(warning))
+	}
+	if err = errors.Join(err, checkConfig(cfg)); err != nil {
+		problems := flatten(err)
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s: %d problem(s)", configPath, len(problems))
+		for _, problem := range problems {
+			fmt.Fprintf(&b, "\n  %v", problem)
+		}
+		return errors.New(b.String())
+	}
+
+	fmt.Fprintf(os.Stdout, "%s: ok (%d sources, %d formats, %d alert rules, %d sinks)\n",
+		configPath, len(cfg.Sources), len(cfg.Formats), len(cfg.Alerts), len(cfg.Sinks))
+	return nil
+}
+
+// checkConfig compiles what config.Load leaves to the components: formats
+// and grok patterns, multiline rules, alert rules, notifiers and sinks.
+func checkConfig(cfg config.Config) error {
+	var errs []error
+	parser, err := parse.NewParser(cfg)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	custom := make(map[string]bool, len(cfg.Formats))
+	for _, format := range cfg.Formats {
+		custom[strings.ToLower(strings.TrimSpace(format.Name))] = true
+	}
+	sources := make(map[string]bool, len(cfg.Sources))
+	for _, src := range cfg.Sources {
+		sources[src.Name] = true
+		name := strings.ToLower(strings.TrimSpace(src.Format))
+		if name != "" && !parser.HasFormat(name) && !custom[name] {
+			errs = append(errs, fmt.Errorf("source %s: unknown format %q", src.Name, src.Format))
+		}
+	}
+	for _, rule := range cfg.Alerts {
+		if rule.SourceName != "" && !sources[rule.SourceName] {
+			errs = append(errs, fmt.Errorf("alert %s: sourceName %q is not a configured source", rule.Name, rule.SourceName))
+		}
+	}
+
+	if _, err := ingest.NewAssembler(cfg.Sources); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := alert.NewEvaluator(cfg.Alerts); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := notify.NewDispatcher(cfg.Alerts); err != nil {
+		errs = append(errs, err)
+	}
+	if sinks, err := sink.NewManager(cfg.Sinks); err != nil {
+		errs = append(errs, err)
+	} else {
+		_ = sinks.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// flatten lists the errors joined into err, however deeply.
+func flatten(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var out []error
+		for _, inner := range joined.Unwrap() {
+			out = append(out, flatten(inner)...)
+		}
+		return out
+	}
+	return []error{err}
+}
@@ -0,0 +1,83 @@
+package alert
+
+import (
+	"sort"
+	"time"
+
+	"go-log-aggregator/internal/parse"
+)
+
+func (s *ruleState) start(rule Rule, now time.Time) {
+	s.started = now
+	// Without grouping the rule covers the whole source, which may be
+	// silent from the start.
+	if rule.GroupBy == "" {
+		if _, ok := s.groups[""]; !ok {
+			s.groups[""] = &groupState{lastSeen: now}
+		}
+	}
+}
+
+// seen records traffic for an absence rule, resolving it if it was firing.
+func (s *ruleState) seen(rule Rule, event parse.StructuredEvent, now time.Time, out []Match) []Match {
+	key := groupKey(rule, event)
+	group, ok := s.groups[key]
+	if !ok {
+		group = &groupState{}
+		s.groups[key] = group
+	}
+	silence := now.Sub(group.lastSeen)
+	if now.After(group.lastSeen) {
+		group.lastSeen = now
+	}
+	group.last = event
+	if !group.firing {
+		return out
+	}
+
+	group.firing = false
+	return append(out, Match{
+		RuleName: rule.Name,
+		Event:    event,
+		State:    StateResolved,
+		Group:    key,
+		Count:    1,
+		Value:    silence.Seconds(),
+		Window:   rule.Window,
+	})
+}
+
+func (s *ruleState) tickAbsence(rule Rule, now time.Time, out []Match) []Match {
+	if s.started.IsZero() || now.Before(s.started.Add(rule.Grace)) {
+		return out
+	}
+
+	keys := make([]string, 0, len(s.groups))
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := s.groups[key]
+		// Traffic seen before Start (e.g. backfill) counts from Start.
+		since := group.lastSeen
+		if since.Before(s.started) {
+			since = s.started
+		}
+		silence := now.Sub(since)
+		if group.firing || silence < rule.Window {
+			continue
+		}
+		group.firing = true
+		out = append(out, Match{
+			RuleName: rule.Name,
+			Event:    group.last,
+			State:    StateFiring,
+			Group:    key,
+			Value:    silence.Seconds(),
+			Window:   rule.Window,
+		})
+	}
+	return out
+}
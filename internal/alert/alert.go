@@ -1,68 +1,311 @@
 package alert
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/filter"
 	"go-log-aggregator/internal/parse"
 )
 
+const (
+	// TypeMatch rules fire once per matching line.
+	TypeMatch = "match"
+	// TypeThreshold rules fire when more than Threshold matches arrive
+	// within Window.
+	TypeThreshold = "threshold"
+	// TypeRatio rules fire when matches divided by all in-scope events
+	// within Window exceeds Threshold.
+	TypeRatio = "ratio"
+	// TypeAbsence rules fire when no matching event arrives for Window.
+	TypeAbsence = "absence"
+)
+
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
 type Rule struct {
 	Name       string
+	Type       string
 	Pattern    *regexp.Regexp
+	Query      filter.Node
 	Severity   string
 	SourceName string
+	Window     time.Duration
+	Threshold  float64
+	GroupBy    string
+	// TotalQuery narrows the denominator of a ratio rule.
+	TotalQuery filter.Node
+	MinEvents  int
+	// Grace delays absence rules after Start.
+	Grace time.Duration
+	// Cooldown, DedupKey and GroupWait throttle per-line rules; see
+	// config.AlertRule.
+	Cooldown   time.Duration
+	DedupKey   []string
+	GroupWait  time.Duration
+	MaxSamples int
 }
 
+// Match is an alert notification. Per-line rules leave State empty;
+// windowed rules report StateFiring once when they cross the threshold and
+// StateResolved once when they drop back.
 type Match struct {
 	RuleName string
 	Event    parse.StructuredEvent
+	State    string
+	Group    string
+	// Count is the number of matches in the window; Value is the count,
+	// the ratio for ratio rules, or seconds of silence for absence rules.
+	Count     int
+	Value     float64
+	Threshold float64
+	Window    time.Duration
+	// Samples holds the first few events a grouped notification covers;
+	// Suppressed counts matches dropped by the cooldown since the
+	// previous notification for the same key.
+	Samples    []parse.StructuredEvent
+	Suppressed int
 }
 
 type Evaluator struct {
-	rules []Rule
+	rules   []Rule
+	configs []config.AlertRule
+	states  []*ruleState
+	started time.Time
 }
 
 func NewEvaluator(rules []config.AlertRule) (*Evaluator, error) {
 	compiled := make([]Rule, 0, len(rules))
+	states := make([]*ruleState, 0, len(rules))
+	var errs []error
 	for _, rule := range rules {
-		if strings.TrimSpace(rule.Pattern) == "" {
-			return nil, fmt.Errorf("alert %s: pattern is required", rule.Name)
+		compiledRule, err := compileRule(rule)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		compiled = append(compiled, compiledRule)
+		states = append(states, newRuleState())
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &Evaluator{rules: compiled, configs: rules, states: states}, nil
+}
+
+func compileRule(rule config.AlertRule) (Rule, error) {
+	compiled := Rule{
+		Name:       rule.Name,
+		Type:       strings.ToLower(strings.TrimSpace(rule.Type)),
+		Severity:   strings.ToLower(strings.TrimSpace(rule.Severity)),
+		SourceName: strings.TrimSpace(rule.SourceName),
+		Threshold:  rule.Threshold,
+		GroupBy:    strings.TrimSpace(rule.GroupBy),
+		MinEvents:  rule.MinEvents,
+		DedupKey:   rule.DedupKey,
+		MaxSamples: rule.MaxSamples,
+	}
+	if compiled.Type == "" {
+		compiled.Type = TypeMatch
+	}
+
+	var err error
+	if strings.TrimSpace(rule.Pattern) == "" && strings.TrimSpace(rule.Query) == "" {
+		if compiled.Type != TypeAbsence || compiled.SourceName == "" {
+			return Rule{}, fmt.Errorf("alert %s: pattern or query is required", rule.Name)
 		}
+	}
+	if strings.TrimSpace(rule.Pattern) != "" {
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
-			return nil, fmt.Errorf("alert %s: invalid pattern: %w", rule.Name, err)
+			return Rule{}, fmt.Errorf("alert %s: invalid pattern: %w", rule.Name, err)
+		}
+		compiled.Pattern = re
+	}
+	if strings.TrimSpace(rule.Query) != "" {
+		query, err := filter.ParseQuery(rule.Query)
+		if err != nil {
+			return Rule{}, fmt.Errorf("alert %s: %w", rule.Name, err)
 		}
-		compiled = append(compiled, Rule{
-			Name:       rule.Name,
-			Pattern:    re,
-			Severity:   strings.ToLower(strings.TrimSpace(rule.Severity)),
-			SourceName: strings.TrimSpace(rule.SourceName),
-		})
-	}
-	return &Evaluator{rules: compiled}, nil
+		compiled.Query = query
+	}
+
+	switch compiled.Type {
+	case TypeMatch:
+		if compiled.Cooldown, err = optionalDuration(rule.Name, "cooldown", rule.Cooldown); err != nil {
+			return Rule{}, err
+		}
+		if compiled.GroupWait, err = optionalDuration(rule.Name, "groupWait", rule.GroupWait); err != nil {
+			return Rule{}, err
+		}
+		if compiled.MaxSamples <= 0 {
+			compiled.MaxSamples = defaultMaxSamples
+		}
+		return compiled, nil
+	case TypeThreshold, TypeRatio, TypeAbsence:
+	default:
+		return Rule{}, fmt.Errorf("alert %s: type must be match, threshold, ratio or absence", rule.Name)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(rule.Window))
+	if err != nil || window <= 0 {
+		return Rule{}, fmt.Errorf("alert %s: window must be a positive duration", rule.Name)
+	}
+	compiled.Window = window
+	if len(compiled.DedupKey) > 0 || rule.Cooldown != "" || rule.GroupWait != "" {
+		return Rule{}, fmt.Errorf("alert %s: cooldown, dedupKey and groupWait apply to match rules only", rule.Name)
+	}
+	if compiled.Threshold < 0 {
+		return Rule{}, fmt.Errorf("alert %s: threshold must not be negative", rule.Name)
+	}
+
+	if compiled.Type == TypeAbsence && strings.TrimSpace(rule.Grace) != "" {
+		grace, err := time.ParseDuration(strings.TrimSpace(rule.Grace))
+		if err != nil || grace < 0 {
+			return Rule{}, fmt.Errorf("alert %s: grace must be a duration", rule.Name)
+		}
+		compiled.Grace = grace
+	}
+
+	if compiled.Type == TypeRatio {
+		if compiled.Threshold > 1 {
+			return Rule{}, fmt.Errorf("alert %s: ratio threshold must be between 0 and 1", rule.Name)
+		}
+		if strings.TrimSpace(rule.TotalQuery) != "" {
+			query, err := filter.ParseQuery(rule.TotalQuery)
+			if err != nil {
+				return Rule{}, fmt.Errorf("alert %s: totalQuery: %w", rule.Name, err)
+			}
+			compiled.TotalQuery = query
+		}
+		if compiled.MinEvents <= 0 {
+			compiled.MinEvents = 1
+		}
+	}
+	return compiled, nil
 }
 
+// Evaluate checks event against every rule. Windowed rules count the event
+// at its parsed Timestamp (ReceivedAt when it has none), so backfilled
+// history older than a rule's window is not counted at all.
 func (e *Evaluator) Evaluate(event parse.StructuredEvent) []Match {
 	if e == nil {
 		return nil
 	}
 
+	now := event.ReceivedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	at := event.Timestamp
+	if at.IsZero() || at.After(now) {
+		at = now
+	}
+
 	matches := make([]Match, 0)
-	for _, rule := range e.rules {
-		if rule.Severity != "" && !strings.EqualFold(event.Severity, rule.Severity) {
-			continue
+	for i, rule := range e.rules {
+		switch rule.Type {
+		case TypeMatch:
+			if !rule.inScope(event) || !rule.matches(event) {
+				continue
+			}
+			if rule.Cooldown == 0 && rule.GroupWait == 0 {
+				matches = append(matches, Match{RuleName: rule.Name, Event: event, Count: 1})
+				continue
+			}
+			matches = e.states[i].throttle(rule, event, now, matches)
+		case TypeThreshold:
+			if rule.inWindow(at, now) && rule.inScope(event) && rule.matches(event) {
+				matches = e.states[i].observe(rule, event, at, true, matches)
+			}
+		case TypeRatio:
+			if rule.inWindow(at, now) && rule.inScope(event) && (rule.TotalQuery == nil || rule.TotalQuery.Matches(event)) {
+				matches = e.states[i].observe(rule, event, at, rule.matches(event), matches)
+			}
+		case TypeAbsence:
+			if rule.inScope(event) && rule.matches(event) {
+				matches = e.states[i].seen(rule, event, now, matches)
+			}
 		}
-		if rule.SourceName != "" && !strings.EqualFold(event.SourceName, rule.SourceName) {
-			continue
-		}
-		if !rule.Pattern.MatchString(event.Raw) && !rule.Pattern.MatchString(event.Message) {
-			continue
+	}
+
+	return matches
+}
+
+// Start arms absence rules; until it is called (e.g. while backfilling)
+// they never fire. Silence is measured from now at the earliest.
+func (e *Evaluator) Start(now time.Time) {
+	if e == nil {
+		return
+	}
+	e.started = now
+	for i, rule := range e.rules {
+		if rule.Type == TypeAbsence {
+			e.states[i].start(rule, now)
 		}
-		matches = append(matches, Match{RuleName: rule.Name, Event: event})
 	}
+}
 
+// Tick advances rules to now: grouped matches whose wait is over are sent,
+// windowed alerts whose windows have drained resolve, and absence rules
+// that have been silent too long fire. Call it periodically.
+func (e *Evaluator) Tick(now time.Time) []Match {
+	if e == nil {
+		return nil
+	}
+
+	matches := make([]Match, 0)
+	for i, rule := range e.rules {
+		switch rule.Type {
+		case TypeMatch:
+			matches = e.states[i].tickThrottle(rule, now, matches)
+		case TypeThreshold, TypeRatio:
+			matches = e.states[i].tick(rule, now, matches)
+		case TypeAbsence:
+			matches = e.states[i].tickAbsence(rule, now, matches)
+		}
+	}
 	return matches
 }
+
+func optionalDuration(rule, name, value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	dur, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || dur < 0 {
+		return 0, fmt.Errorf("alert %s: %s must be a duration", rule, name)
+	}
+	return dur, nil
+}
+
+// inWindow reports whether an event at at still falls in the rule's window
+// ending now.
+func (r Rule) inWindow(at, now time.Time) bool {
+	return at.After(now.Add(-r.Window))
+}
+
+// inScope applies the rule's source restriction.
+func (r Rule) inScope(event parse.StructuredEvent) bool {
+	return r.SourceName == "" || strings.EqualFold(event.SourceName, r.SourceName)
+}
+
+func (r Rule) matches(event parse.StructuredEvent) bool {
+	if r.Severity != "" && !strings.EqualFold(event.Severity, r.Severity) {
+		return false
+	}
+	if r.Pattern != nil && !r.Pattern.MatchString(event.Raw) && !r.Pattern.MatchString(event.Message) {
+		return false
+	}
+	if r.Query != nil && !r.Query.Matches(event) {
+		return false
+	}
+	return true
+}
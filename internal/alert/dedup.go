@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/filter"
+	"go-log-aggregator/internal/parse"
+)
+
+const (
+	defaultMaxSamples = 5
+	// dedupRetention bounds how long a key with unreported suppressed
+	// matches is remembered.
+	dedupRetention = 24 * time.Hour
+)
+
+type dedupState struct {
+	lastSent   time.Time
+	suppressed int
+	pending    *Match
+	pendingAt  time.Time
+}
+
+func dedupKey(rule Rule, event parse.StructuredEvent) string {
+	if len(rule.DedupKey) == 0 {
+		return ""
+	}
+	values := make([]string, 0, len(rule.DedupKey))
+	for _, field := range rule.DedupKey {
+		value, _ := filter.FieldValue(event, field)
+		values = append(values, value)
+	}
+	return strings.Join(values, ",")
+}
+
+// throttle applies a match rule's cooldown and grouping to one matching
+// event, appending a notification when one is due right away.
+func (s *ruleState) throttle(rule Rule, event parse.StructuredEvent, now time.Time, out []Match) []Match {
+	key := dedupKey(rule, event)
+	state, ok := s.dedup[key]
+	if !ok {
+		state = &dedupState{}
+		s.dedup[key] = state
+	}
+
+	if state.pending != nil {
+		state.pending.Count++
+		if len(state.pending.Samples) < rule.MaxSamples {
+			state.pending.Samples = append(state.pending.Samples, event)
+		}
+		return out
+	}
+	if !state.lastSent.IsZero() && now.Sub(state.lastSent) < rule.Cooldown {
+		state.suppressed++
+		return out
+	}
+
+	match := Match{
+		RuleName:   rule.Name,
+		Event:      event,
+		Group:      key,
+		Count:      1,
+		Samples:    []parse.StructuredEvent{event},
+		Suppressed: state.suppressed,
+	}
+	state.suppressed = 0
+	if rule.GroupWait > 0 {
+		state.pending = &match
+		state.pendingAt = now
+		return out
+	}
+	state.lastSent = now
+	return append(out, match)
+}
+
+func (s *ruleState) tickThrottle(rule Rule, now time.Time, out []Match) []Match {
+	keys := make([]string, 0, len(s.dedup))
+	for key := range s.dedup {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		state := s.dedup[key]
+		if state.pending != nil && !now.Before(state.pendingAt.Add(rule.GroupWait)) {
+			out = append(out, *state.pending)
+			state.pending = nil
+			state.lastSent = now
+			continue
+		}
+		if state.pending != nil {
+			continue
+		}
+		idle := now.Sub(state.lastSent)
+		if idle >= rule.Cooldown && (state.suppressed == 0 || idle >= dedupRetention) {
+			delete(s.dedup, key)
+		}
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package alert
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"go-log-aggregator/internal/config"
+)
+
+// Reload compiles rules into a new evaluator. Rules whose definition is
+// unchanged keep their windows, firing groups and cooldowns; notify
+// settings don't count as a change. Groups still firing on rules that
+// were removed or changed come back as resolved matches. e itself is left
+// untouched, so the new evaluator can be discarded on a later error.
+func (e *Evaluator) Reload(rules []config.AlertRule, now time.Time) (*Evaluator, []Match, error) {
+	next, err := NewEvaluator(rules)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e == nil {
+		return next, nil, nil
+	}
+
+	kept := make(map[int]bool)
+	for i, rule := range next.configs {
+		if j := e.ruleIndex(rule.Name); j >= 0 && sameRule(e.configs[j], rule) {
+			next.states[i] = e.states[j]
+			kept[j] = true
+		}
+	}
+
+	next.started = e.started
+	if !e.started.IsZero() {
+		for i, rule := range next.rules {
+			if rule.Type == TypeAbsence && next.states[i].started.IsZero() {
+				next.states[i].start(rule, now)
+			}
+		}
+	}
+
+	matches := make([]Match, 0)
+	for j, rule := range e.rules {
+		if kept[j] {
+			continue
+		}
+		matches = e.states[j].retire(rule, now, matches)
+	}
+	return next, matches, nil
+}
+
+func (e *Evaluator) ruleIndex(name string) int {
+	for i, rule := range e.configs {
+		if rule.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameRule(a, b config.AlertRule) bool {
+	a.Notify, b.Notify = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// retire resolves the firing groups of a rule that is going away.
+func (s *ruleState) retire(rule Rule, now time.Time, out []Match) []Match {
+	keys := make([]string, 0, len(s.groups))
+	for key, group := range s.groups {
+		if group.firing {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := s.groups[key]
+		match := Match{
+			RuleName:  rule.Name,
+			Event:     group.last,
+			State:     StateResolved,
+			Group:     key,
+			Threshold: rule.Threshold,
+			Window:    rule.Window,
+		}
+		if group.matched != nil {
+			group.matched.expire(now)
+			match.Count = group.matched.total
+			match.Value = float64(match.Count)
+		}
+		out = append(out, match)
+	}
+	return out
+}
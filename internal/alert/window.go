@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"go-log-aggregator/internal/filter"
+	"go-log-aggregator/internal/parse"
+)
+
+// windowSlots is how finely a window is divided; counts expire one slot
+// at a time.
+const windowSlots = 60
+
+// slidingCounter counts events over the trailing window in fixed slots, so
+// memory stays bounded however busy the source is.
+type slidingCounter struct {
+	window time.Duration
+	slot   time.Duration
+	slots  []slotCount
+	total  int
+}
+
+type slotCount struct {
+	start time.Time
+	n     int
+}
+
+func newSlidingCounter(window time.Duration) *slidingCounter {
+	slot := window / windowSlots
+	if slot <= 0 {
+		slot = window
+	}
+	return &slidingCounter{window: window, slot: slot}
+}
+
+// add counts an event at at, which may be older than events already
+// counted when sources are read out of order.
+func (c *slidingCounter) add(at time.Time) {
+	c.expire(at)
+	start := at.Truncate(c.slot)
+	i := len(c.slots)
+	for i > 0 && c.slots[i-1].start.After(start) {
+		i--
+	}
+	if i > 0 && c.slots[i-1].start.Equal(start) {
+		c.slots[i-1].n++
+	} else {
+		c.slots = slices.Insert(c.slots, i, slotCount{start: start, n: 1})
+	}
+	c.total++
+}
+
+func (c *slidingCounter) expire(now time.Time) {
+	cutoff := now.Add(-c.window)
+	drop := 0
+	for drop < len(c.slots) && !c.slots[drop].start.Add(c.slot).After(cutoff) {
+		c.total -= c.slots[drop].n
+		drop++
+	}
+	if drop > 0 {
+		c.slots = append(c.slots[:0], c.slots[drop:]...)
+	}
+}
+
+type groupState struct {
+	matched  *slidingCounter
+	total    *slidingCounter
+	lastSeen time.Time
+	firing   bool
+	last     parse.StructuredEvent
+}
+
+// ruleState tracks a rule per group or dedup key.
+type ruleState struct {
+	groups  map[string]*groupState
+	dedup   map[string]*dedupState
+	started time.Time
+}
+
+func newRuleState() *ruleState {
+	return &ruleState{
+		groups: make(map[string]*groupState),
+		dedup:  make(map[string]*dedupState),
+	}
+}
+
+func (s *ruleState) observe(rule Rule, event parse.StructuredEvent, now time.Time, matched bool, out []Match) []Match {
+	key := groupKey(rule, event)
+	group, ok := s.groups[key]
+	if !ok {
+		group = &groupState{matched: newSlidingCounter(rule.Window)}
+		if rule.Type == TypeRatio {
+			group.total = newSlidingCounter(rule.Window)
+		}
+		s.groups[key] = group
+	}
+
+	if matched {
+		group.matched.add(now)
+		group.last = event
+	} else {
+		group.matched.expire(now)
+	}
+	if group.total != nil {
+		group.total.add(now)
+	}
+	return s.evaluate(rule, key, group, out)
+}
+
+func (s *ruleState) tick(rule Rule, now time.Time, out []Match) []Match {
+	keys := make([]string, 0, len(s.groups))
+	for key := range s.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		group := s.groups[key]
+		group.matched.expire(now)
+		if group.total != nil {
+			group.total.expire(now)
+		}
+		out = s.evaluate(rule, key, group, out)
+		if !group.firing && group.matched.total == 0 && (group.total == nil || group.total.total == 0) {
+			delete(s.groups, key)
+		}
+	}
+	return out
+}
+
+func (s *ruleState) evaluate(rule Rule, key string, group *groupState, out []Match) []Match {
+	count := group.matched.total
+	value := float64(count)
+	breached := value > rule.Threshold
+	if rule.Type == TypeRatio {
+		total := group.total.total
+		value = 0
+		if total > 0 {
+			value = float64(count) / float64(total)
+		}
+		breached = total >= rule.MinEvents && value > rule.Threshold
+	}
+
+	var state string
+	switch {
+	case breached && !group.firing:
+		state = StateFiring
+	case !breached && group.firing:
+		state = StateResolved
+	default:
+		return out
+	}
+	group.firing = breached
+
+	return append(out, Match{
+		RuleName:  rule.Name,
+		Event:     group.last,
+		State:     state,
+		Group:     key,
+		Count:     count,
+		Value:     value,
+		Threshold: rule.Threshold,
+		Window:    rule.Window,
+	})
+}
+
+func groupKey(rule Rule, event parse.StructuredEvent) string {
+	if rule.GroupBy == "" {
+		return ""
+	}
+	key, _ := filter.FieldValue(event, rule.GroupBy)
+	return key
+}
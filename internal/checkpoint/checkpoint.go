@@ -0,0 +1,288 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go-log-aggregator/internal/fsutil"
+)
+
+// FingerprintBytes is how much of the head of a file is hashed to recognise
+// it across restarts.
+const FingerprintBytes = 1024
+
+type FileID struct {
+	Device          uint64 `json:"device,omitempty"`
+	Inode           uint64 `json:"inode,omitempty"`
+	Fingerprint     string `json:"fingerprint,omitempty"`
+	FingerprintSize int    `json:"fingerprintSize,omitempty"`
+}
+
+type Position struct {
+	FileID
+	Offset    int64     `json:"offset"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Store struct {
+	mu        sync.Mutex
+	path      string
+	positions map[string]Position
+	dirty     bool
+}
+
+// Open loads the checkpoints saved at path. With an empty path they are
+// kept in memory only, which still lets backfill hand over to the tailer.
+func Open(path string) (*Store, error) {
+	store := &Store{
+		path:      path,
+		positions: make(map[string]Position),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store.positions); err != nil {
+		return nil, fmt.Errorf("parse checkpoints: %w", err)
+	}
+	return store, nil
+}
+
+// Identify returns the identity of an open file without moving its offset.
+func Identify(file *os.File) (FileID, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return FileID{}, err
+	}
+
+	id := fileIdentity(info)
+	size := info.Size()
+	if size > FingerprintBytes {
+		size = FingerprintBytes
+	}
+	sum, err := fingerprint(file, int(size))
+	if err != nil {
+		return FileID{}, err
+	}
+	id.Fingerprint = sum
+	id.FingerprintSize = int(size)
+	return id, nil
+}
+
+func fingerprint(file *os.File, size int) (string, error) {
+	if size <= 0 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if n < size {
+		return "", io.ErrUnexpectedEOF
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HeadChanged reports whether the head of file no longer matches id, i.e.
+// the file was truncated or rewritten in place.
+func HeadChanged(file *os.File, id FileID) bool {
+	if id.FingerprintSize == 0 {
+		return false
+	}
+	sum, err := fingerprint(file, id.FingerprintSize)
+	if err != nil {
+		return true
+	}
+	return sum != id.Fingerprint
+}
+
+func sameInode(a, b FileID) bool {
+	if a.Inode == 0 || b.Inode == 0 {
+		return true
+	}
+	return a.Inode == b.Inode && a.Device == b.Device
+}
+
+func (s *Store) Has(path string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.positions[path]
+	return ok
+}
+
+// Resume reports where reading of file should continue. ok is false when
+// no checkpoint exists for path. When the checkpoint belongs to a different
+// file (replaced, or truncated below the saved offset) the offset is 0 so
+// the new content is read in full.
+func (s *Store) Resume(path string, file *os.File) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+
+	s.mu.Lock()
+	pos, ok := s.positions[path]
+	s.mu.Unlock()
+	if !ok {
+		return 0, false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, true
+	}
+	if !sameInode(fileIdentity(info), pos.FileID) {
+		return 0, true
+	}
+	if info.Size() < pos.Offset || int64(pos.FingerprintSize) > info.Size() {
+		return 0, true
+	}
+	sum, err := fingerprint(file, pos.FingerprintSize)
+	if err != nil || sum != pos.Fingerprint {
+		return 0, true
+	}
+	return pos.Offset, true
+}
+
+// Track records that path is now being read from offset of the file
+// identified by id.
+func (s *Store) Track(path string, id FileID, offset int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions[path] = Position{FileID: id, Offset: offset, UpdatedAt: time.Now()}
+	s.dirty = true
+}
+
+// UpdateIdentity refreshes the identity of path while keeping its offset,
+// used to widen the fingerprint as a young file grows.
+func (s *Store) UpdateIdentity(path string, id FileID) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos, ok := s.positions[path]
+	if !ok {
+		return
+	}
+	pos.FileID = id
+	s.positions[path] = pos
+	s.dirty = true
+}
+
+// Commit advances the saved offset of path once the line ending at offset
+// has been fully handled. Lines read from a file that has since been
+// rotated away (a different inode) are ignored.
+func (s *Store) Commit(path string, id FileID, offset int64) {
+	if s == nil || offset <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos, ok := s.positions[path]
+	if !ok || !sameInode(pos.FileID, id) {
+		return
+	}
+	pos.Offset = offset
+	pos.UpdatedAt = time.Now()
+	s.positions[path] = pos
+	s.dirty = true
+}
+
+func (s *Store) Forget(path string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.positions[path]; ok {
+		delete(s.positions, path)
+		s.dirty = true
+	}
+}
+
+func (s *Store) Get(path string) (Position, bool) {
+	if s == nil {
+		return Position{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos, ok := s.positions[path]
+	return pos, ok
+}
+
+// Flush writes the checkpoints to disk if anything changed, replacing the
+// previous file atomically.
+func (s *Store) Flush() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.dirty || s.path == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(s.positions, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal checkpoints: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return fmt.Errorf("write checkpoints: %w", err)
+	}
+	return nil
+}
+
+// Run flushes periodically until ctx is done. Callers flush once more on
+// shutdown after the last event has been handled.
+func (s *Store) Run(ctx context.Context, interval time.Duration, errs chan<- error) {
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil && errs != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}
+	}
+}
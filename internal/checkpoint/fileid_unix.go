@@ -0,0 +1,16 @@
+//go:build !windows
+
+package checkpoint
+
+import (
+	"os"
+	"syscall"
+)
+
+func fileIdentity(info os.FileInfo) FileID {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}
+	}
+	return FileID{Device: uint64(stat.Dev), Inode: uint64(stat.Ino)}
+}
@@ -1,56 +1,297 @@
 package config
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Sources []Source    `json:"sources"`
-	Alerts  []AlertRule `json:"alerts,omitempty"`
+	Sources          []Source    `json:"sources"`
+	Alerts           []AlertRule `json:"alerts,omitempty"`
+	Formats          []Format    `json:"formats,omitempty"`
+	GrokPatternFiles []string    `json:"grokPatternFiles,omitempty"`
+	CheckpointPath   string      `json:"checkpointPath,omitempty"`
+	Store            *Store      `json:"store,omitempty"`
+	// SilencesPath persists alert silences; without it they are lost on
+	// restart.
+	SilencesPath string `json:"silencesPath,omitempty"`
+	Sinks        []Sink `json:"sinks,omitempty"`
+	// Include lists more config files or globs, relative to the including
+	// file. Lists from included files are appended in order; other
+	// settings are taken from the last file that sets them.
+	Include []string `json:"include,omitempty"`
+	// Files are the config file and include patterns the config was
+	// loaded from.
+	Files []string `json:"-"`
+	// Warnings name keys that were not recognized and so were ignored,
+	// e.g. a misspelling or a setting from a newer version.
+	Warnings []string `json:"-"`
 }
 
+// Store configures the on-disk event history behind the dashboard. Without
+// it, history is kept in memory only.
+type Store struct {
+	Path     string `json:"path"`
+	MaxAge   string `json:"maxAge,omitempty"`
+	MaxBytes int64  `json:"maxBytes,omitempty"`
+}
+
+// Sink forwards every event to a file or an external system. Type is
+// "file", "elasticsearch", "loki" or "syslog".
+type Sink struct {
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+	// File: rotated when it exceeds MaxSize bytes or gets older than
+	// MaxAge; MaxFiles rotated files are kept, gzipped with Compress.
+	Path     string `json:"path,omitempty"`
+	MaxSize  int64  `json:"maxSize,omitempty"`
+	MaxAge   string `json:"maxAge,omitempty"`
+	MaxFiles int    `json:"maxFiles,omitempty"`
+	Compress bool   `json:"compress,omitempty"`
+	// Format ("json", "logfmt", "text" or "template" with Template) sets
+	// the line written by file, loki and syslog sinks. Elasticsearch
+	// always gets JSON.
+	Format   string `json:"format,omitempty"`
+	Template string `json:"template,omitempty"`
+	// Elasticsearch and Loki.
+	URL     string            `json:"url,omitempty"`
+	Index   string            `json:"index,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Syslog: Protocol is "udp" (default) or "tcp".
+	Address  string `json:"address,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Tag      string `json:"tag,omitempty"`
+	// Delivery: events are sent in batches of up to BatchSize (default
+	// 500) at least every FlushInterval (default 1s). Failed batches are
+	// retried Retries times (default 5) with Backoff (default 1s,
+	// doubling). At most BufferSize (default 10000) events wait per sink;
+	// beyond that new events are dropped.
+	BatchSize     int    `json:"batchSize,omitempty"`
+	FlushInterval string `json:"flushInterval,omitempty"`
+	BufferSize    int    `json:"bufferSize,omitempty"`
+	Retries       *int   `json:"retries,omitempty"`
+	Backoff       string `json:"backoff,omitempty"`
+	Timeout       string `json:"timeout,omitempty"`
+}
+
+const (
+	SourceTypeFile   = "file"
+	SourceTypeSyslog = "syslog"
+	SourceTypeHTTP   = "http"
+)
+
 type Source struct {
-	Name   string `json:"name"`
-	Path   string `json:"path"`
+	Name string `json:"name"`
+	// Type is "file" (default), "syslog" for a network listener, or "http"
+	// for lines pushed to the web server's ingest endpoint.
+	Type   string `json:"type,omitempty"`
+	Path   string `json:"path,omitempty"`
 	Format string `json:"format"`
+	// Pattern is the grok expression used when Format is "grok".
+	Pattern string `json:"pattern,omitempty"`
+	// Address and Protocol ("udp", "tcp" or empty for both) configure a
+	// syslog listener.
+	Address   string     `json:"address,omitempty"`
+	Protocol  string     `json:"protocol,omitempty"`
+	Multiline *Multiline `json:"multiline,omitempty"`
+}
+
+// Kind returns the normalized source type.
+func (s Source) Kind() string {
+	kind := strings.ToLower(strings.TrimSpace(s.Type))
+	if kind == "" {
+		return SourceTypeFile
+	}
+	return kind
+}
+
+// Multiline joins consecutive lines (e.g. stack traces) into one event.
+// A line matching StartPattern begins a new event; otherwise a line
+// matching ContinuationPattern is appended to the current one. Negate
+// inverts the pattern matches.
+type Multiline struct {
+	StartPattern        string `json:"startPattern,omitempty"`
+	ContinuationPattern string `json:"continuationPattern,omitempty"`
+	Negate              bool   `json:"negate,omitempty"`
+	MaxLines            int    `json:"maxLines,omitempty"`
+	FlushTimeout        string `json:"flushTimeout,omitempty"`
+}
+
+// Format defines a named custom log format as a regex with named groups,
+// or a grok expression when Type is "grok". The *Field settings name the
+// groups that populate the event timestamp (parsed with TimestampLayout),
+// severity and message; all other named groups become fields.
+type Format struct {
+	Name            string `json:"name"`
+	Type            string `json:"type,omitempty"`
+	Pattern         string `json:"pattern"`
+	TimestampField  string `json:"timestampField,omitempty"`
+	TimestampLayout string `json:"timestampLayout,omitempty"`
+	SeverityField   string `json:"severityField,omitempty"`
+	MessageField    string `json:"messageField,omitempty"`
 }
 
+// AlertRule selects events by Pattern and/or Query (plus Severity and
+// SourceName). Type "match" (default) alerts on every matching line;
+// "threshold" alerts when more than Threshold matches arrive within
+// Window, "ratio" when matches over all events from the source (or
+// TotalQuery) exceed Threshold, and "absence" when nothing matches for
+// Window (not before Grace has passed since startup). Windowed rules can
+// track each GroupBy field value separately.
 type AlertRule struct {
-	Name       string `json:"name"`
-	Pattern    string `json:"pattern"`
-	Severity   string `json:"severity,omitempty"`
-	SourceName string `json:"sourceName,omitempty"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type,omitempty"`
+	Pattern    string  `json:"pattern,omitempty"`
+	Query      string  `json:"query,omitempty"`
+	Severity   string  `json:"severity,omitempty"`
+	SourceName string  `json:"sourceName,omitempty"`
+	Window     string  `json:"window,omitempty"`
+	Threshold  float64 `json:"threshold,omitempty"`
+	GroupBy    string  `json:"groupBy,omitempty"`
+	TotalQuery string  `json:"totalQuery,omitempty"`
+	MinEvents  int     `json:"minEvents,omitempty"`
+	Grace      string  `json:"grace,omitempty"`
+	// For match rules: after a notification, further matches with the
+	// same DedupKey field values are dropped for Cooldown; with GroupWait,
+	// matches arriving within it are sent as one notification carrying a
+	// count and up to MaxSamples events.
+	Cooldown   string   `json:"cooldown,omitempty"`
+	DedupKey   []string `json:"dedupKey,omitempty"`
+	GroupWait  string   `json:"groupWait,omitempty"`
+	MaxSamples int      `json:"maxSamples,omitempty"`
+	// Notify lists the channels that receive this rule's alerts.
+	Notify []Notifier `json:"notify,omitempty"`
+}
+
+// Notifier configures one alert channel. Type is "webhook" (JSON POST),
+// "slack" (Slack or Mattermost incoming webhook), "email" (SMTP) or "exec"
+// (run Command). Template and Subject are Go templates over alert.Match.
+type Notifier struct {
+	Type     string            `json:"type"`
+	Template string            `json:"template,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Channel  string            `json:"channel,omitempty"`
+	Username string            `json:"username,omitempty"`
+	// Retries (default 3) and Backoff (default 1s, doubling) apply to
+	// webhook and slack delivery.
+	Retries      *int     `json:"retries,omitempty"`
+	Backoff      string   `json:"backoff,omitempty"`
+	Timeout      string   `json:"timeout,omitempty"`
+	SMTPAddr     string   `json:"smtpAddr,omitempty"`
+	SMTPUser     string   `json:"smtpUser,omitempty"`
+	SMTPPassword string   `json:"smtpPassword,omitempty"`
+	From         string   `json:"from,omitempty"`
+	To           []string `json:"to,omitempty"`
+	Subject      string   `json:"subject,omitempty"`
+	Command      []string `json:"command,omitempty"`
 }
 
+// Load reads the config at path, YAML for .yaml and .yml files and JSON
+// otherwise, together with the files it includes, and validates it. When
+// only validation fails, the config is returned along with the errors.
+// Files is filled in even when loading fails, so the files can be watched
+// for a fix.
 func Load(path string) (Config, error) {
 	if strings.TrimSpace(path) == "" {
 		return Config{}, fmt.Errorf("config path is required")
 	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return Config{}, fmt.Errorf("read config: %w", err)
+	l := newLoader()
+	var cfg Config
+	if err := l.load(path, &cfg); err != nil {
+		return Config{Files: l.files, Warnings: l.warnings}, err
 	}
+	cfg.Include = nil
+	cfg.Files = l.files
+	cfg.Warnings = l.warnings
+	if err := validate(&cfg, l.at); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return Config{}, fmt.Errorf("parse config: %w", err)
+// validate checks cfg and fills in defaults. at names the file and key
+// path of an entry, e.g. "conf.d/app.yaml: sources[0]".
+func validate(cfg *Config, at func(key string, i int) string) error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
 	}
 
+	names := make(map[string]string, len(cfg.Sources))
 	for i, src := range cfg.Sources {
 		if strings.TrimSpace(src.Name) == "" {
-			return Config{}, fmt.Errorf("source[%d] name is required", i)
+			fail("%s.name is required", at("sources", i))
+		} else if first, ok := names[src.Name]; ok {
+			fail("%s.name %q is already used by %s", at("sources", i), src.Name, first)
+		} else {
+			names[src.Name] = at("sources", i)
 		}
-		if strings.TrimSpace(src.Path) == "" {
-			return Config{}, fmt.Errorf("source[%d] path is required", i)
+		switch src.Kind() {
+		case SourceTypeFile:
+			if strings.TrimSpace(src.Path) == "" {
+				fail("%s.path is required", at("sources", i))
+			}
+		case SourceTypeSyslog:
+			if strings.TrimSpace(src.Address) == "" {
+				fail("%s.address is required for syslog sources", at("sources", i))
+			}
+			switch strings.ToLower(strings.TrimSpace(src.Protocol)) {
+			case "", "udp", "tcp":
+			default:
+				fail("%s.protocol must be udp or tcp", at("sources", i))
+			}
+			if strings.TrimSpace(src.Format) == "" {
+				cfg.Sources[i].Format = "syslog"
+				src.Format = "syslog"
+			}
+		case SourceTypeHTTP:
+			if strings.TrimSpace(src.Format) == "" {
+				cfg.Sources[i].Format = "json"
+				src.Format = "json"
+			}
+		default:
+			fail("%s.type must be file, syslog or http", at("sources", i))
+			continue
 		}
 		if strings.TrimSpace(src.Format) == "" {
-			return Config{}, fmt.Errorf("source[%d] format is required", i)
+			fail("%s.format is required", at("sources", i))
+		} else if strings.EqualFold(strings.TrimSpace(src.Format), "grok") && strings.TrimSpace(src.Pattern) == "" {
+			fail("%s.pattern is required for grok format", at("sources", i))
 		}
 	}
 
-	return cfg, nil
+	for i, format := range cfg.Formats {
+		if strings.TrimSpace(format.Name) == "" {
+			fail("%s.name is required", at("formats", i))
+		}
+		if strings.TrimSpace(format.Pattern) == "" {
+			fail("%s.pattern is required", at("formats", i))
+		}
+		switch strings.ToLower(strings.TrimSpace(format.Type)) {
+		case "", "regex", "grok":
+		default:
+			fail("%s.type must be regex or grok", at("formats", i))
+		}
+	}
+
+	if cfg.Store != nil {
+		if strings.TrimSpace(cfg.Store.Path) == "" {
+			fail("%s.path is required", at("store", -1))
+		}
+		if cfg.Store.MaxAge != "" {
+			if _, err := time.ParseDuration(cfg.Store.MaxAge); err != nil {
+				fail("%s.maxAge: %w", at("store", -1), err)
+			}
+		}
+		if cfg.Store.MaxBytes < 0 {
+			fail("%s.maxBytes must not be negative", at("store", -1))
+		}
+	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,423 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// errUnknownKey marks a key checkTree found no field for. It is reported
+// as a warning so configs written for a newer version still load.
+var errUnknownKey = errors.New("unknown key")
+
+// envPattern matches ${VAR} and ${VAR:-default}; $${ is a literal ${.
+var envPattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// patternFields hold regular expressions and grok expressions, which are
+// left unexpanded so a `${` in them reaches the pattern as written.
+var patternFields = map[string]bool{"Pattern": true, "StartPattern": true, "ContinuationPattern": true}
+
+// loader reads a config file and its includes, remembering where every
+// list entry and setting came from for error messages.
+type loader struct {
+	seen     map[string]bool
+	files    []string
+	warnings []string
+	origins  map[string][]string
+	setBy    map[string]string
+}
+
+func newLoader() *loader {
+	return &loader{
+		seen:    make(map[string]bool),
+		origins: make(map[string][]string),
+		setBy:   make(map[string]string),
+	}
+}
+
+// load merges the file at path into cfg, then its includes depth-first in
+// the order listed, with the matches of a glob in lexical order.
+func (l *loader) load(path string, cfg *Config) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("resolve config path: %w", err)
+	}
+	if l.seen[abs] {
+		return fmt.Errorf("%s is included more than once", path)
+	}
+	l.seen[abs] = true
+	l.files = append(l.files, abs)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	tree, err := decode(path, data)
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if include, ok := tree["include"].(string); ok {
+		tree["include"] = []any{include}
+	}
+	expanded := expandTree(tree, reflect.TypeOf(Config{}))
+	var errs []error
+	for _, err := range checkTree(expanded, reflect.TypeOf(Config{}), "") {
+		if errors.Is(err, errUnknownKey) {
+			l.warnings = append(l.warnings, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", path, err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	raw, err := json.Marshal(expanded)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	var part Config
+	if err := json.Unmarshal(raw, &part); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	l.merge(cfg, part, path)
+
+	for i, pattern := range part.Include {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		if !hasMeta(pattern) {
+			if err := l.load(pattern, cfg); err != nil {
+				return err
+			}
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: include[%d]: %w", path, i, err)
+		}
+		if abs, err := filepath.Abs(pattern); err == nil {
+			l.files = append(l.files, abs)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if err := l.load(match, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge appends the lists of part to cfg and takes its other settings
+// when set.
+func (l *loader) merge(cfg *Config, part Config, file string) {
+	track := func(key string, n int) {
+		for i := 0; i < n; i++ {
+			l.origins[key] = append(l.origins[key], fmt.Sprintf("%s: %s[%d]", file, key, i))
+		}
+	}
+	cfg.Sources = append(cfg.Sources, part.Sources...)
+	track("sources", len(part.Sources))
+	cfg.Alerts = append(cfg.Alerts, part.Alerts...)
+	track("alerts", len(part.Alerts))
+	cfg.Formats = append(cfg.Formats, part.Formats...)
+	track("formats", len(part.Formats))
+	cfg.GrokPatternFiles = append(cfg.GrokPatternFiles, part.GrokPatternFiles...)
+	track("grokPatternFiles", len(part.GrokPatternFiles))
+	cfg.Sinks = append(cfg.Sinks, part.Sinks...)
+	track("sinks", len(part.Sinks))
+
+	if part.CheckpointPath != "" {
+		cfg.CheckpointPath = part.CheckpointPath
+		l.setBy["checkpointPath"] = file
+	}
+	if part.Store != nil {
+		cfg.Store = part.Store
+		l.setBy["store"] = file
+	}
+	if part.SilencesPath != "" {
+		cfg.SilencesPath = part.SilencesPath
+		l.setBy["silencesPath"] = file
+	}
+}
+
+// at locates list entry i of key, or the setting key when i < 0.
+func (l *loader) at(key string, i int) string {
+	if i < 0 {
+		return l.setBy[key] + ": " + key
+	}
+	if i < len(l.origins[key]) {
+		return l.origins[key][i]
+	}
+	return fmt.Sprintf("%s[%d]", key, i)
+}
+
+func decode(path string, data []byte) (map[string]any, error) {
+	var tree any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		tree = normalizeYAML(tree)
+	default:
+		if err := json.Unmarshal(data, &tree); err != nil {
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) {
+				line := bytes.Count(data[:min(int(syntax.Offset), len(data))], []byte("\n")) + 1
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			return nil, err
+		}
+	}
+	if tree == nil {
+		return map[string]any{}, nil
+	}
+	object, ok := tree.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top level must be an object")
+	}
+	return object, nil
+}
+
+// normalizeYAML turns YAML values into what encoding/json would produce.
+func normalizeYAML(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeYAML(item)
+		}
+		return v
+	case map[any]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return out
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// expandTree replaces environment references in every string value except
+// pattern fields. A
+// reference standing in for a number or a boolean, e.g. "${PORT:-8080}",
+// is converted to one so it decodes into the field; t is the type the
+// value will be read into, nil when unknown.
+func expandTree(value any, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	kind := reflect.Invalid
+	if t != nil {
+		kind = t.Kind()
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			var elem reflect.Type
+			switch kind {
+			case reflect.Struct:
+				if field, ok := jsonField(t, key); ok {
+					if patternFields[field.Name] {
+						continue
+					}
+					elem = field.Type
+				}
+			case reflect.Map:
+				elem = t.Elem()
+			}
+			v[key] = expandTree(item, elem)
+		}
+		return v
+	case []any:
+		var elem reflect.Type
+		if kind == reflect.Slice {
+			elem = t.Elem()
+		}
+		for i, item := range v {
+			v[i] = expandTree(item, elem)
+		}
+		return v
+	case string:
+		expanded := expandEnv(v)
+		if expanded == v {
+			return v
+		}
+		return coerceScalar(strings.TrimSpace(expanded), kind, expanded)
+	default:
+		return v
+	}
+}
+
+// coerceScalar parses value as kind, returning fallback when kind is not a
+// number or boolean or value doesn't parse; checkTree then reports it.
+func coerceScalar(value string, kind reflect.Kind, fallback string) any {
+	switch kind {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// expandEnv replaces ${VAR} with the variable's value and ${VAR:-default}
+// with default when VAR is unset or empty.
+func expandEnv(value string) string {
+	return envPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if ref == "$${" {
+			return "${"
+		}
+		parts := envPattern.FindStringSubmatch(ref)
+		if env, ok := os.LookupEnv(parts[1]); ok && env != "" {
+			return env
+		}
+		return parts[2]
+	})
+}
+
+// checkTree compares a decoded document with the type it will be read
+// into, reporting unknown keys and mistyped values by key path.
+func checkTree(value any, t reflect.Type, path string) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if value == nil {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []error{typeError(path, "an object", value)}
+		}
+		var errs []error
+		for _, key := range sortedKeys(object) {
+			field, ok := jsonField(t, key)
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: %w", joinPath(path, key), errUnknownKey))
+				continue
+			}
+			errs = append(errs, checkTree(object[key], field.Type, joinPath(path, key))...)
+		}
+		return errs
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []error{typeError(path, "an object", value)}
+		}
+		var errs []error
+		for _, key := range sortedKeys(object) {
+			errs = append(errs, checkTree(object[key], t.Elem(), joinPath(path, key))...)
+		}
+		return errs
+	case reflect.Slice:
+		items, ok := value.([]any)
+		if !ok {
+			return []error{typeError(path, "a list", value)}
+		}
+		var errs []error
+		for i, item := range items {
+			errs = append(errs, checkTree(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return errs
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return []error{typeError(path, "a string", value)}
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return []error{typeError(path, "true or false", value)}
+		}
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		switch value.(type) {
+		case float64, int, int64, uint64:
+		default:
+			return []error{typeError(path, "a number", value)}
+		}
+	}
+	return nil
+}
+
+// jsonField finds the field encoding/json would decode key into.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func typeError(path, want string, value any) error {
+	got := "a number"
+	switch value.(type) {
+	case string:
+		got = "a string"
+	case bool:
+		got = "a boolean"
+	case map[string]any:
+		got = "an object"
+	case []any:
+		got = "a list"
+	}
+	return fmt.Errorf("%s: expected %s, got %s", path, want, got)
+}
+
+func sortedKeys(object map[string]any) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func hasMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of events an editor save produces.
+const watchDebounce = 500 * time.Millisecond
+
+// SourceDiff lists the sources that differ between two configs, matched
+// by name. Changed holds the new definitions.
+type SourceDiff struct {
+	Added   []Source
+	Removed []Source
+	Changed []Source
+}
+
+func (d SourceDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+func DiffSources(old, next []Source) SourceDiff {
+	var diff SourceDiff
+	previous := make(map[string]Source, len(old))
+	for _, src := range old {
+		previous[src.Name] = src
+	}
+	seen := make(map[string]bool, len(next))
+	for _, src := range next {
+		seen[src.Name] = true
+		prev, ok := previous[src.Name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, src)
+		case !reflect.DeepEqual(prev, src):
+			diff.Changed = append(diff.Changed, src)
+		}
+	}
+	for _, src := range old {
+		if !seen[src.Name] {
+			diff.Removed = append(diff.Removed, src)
+		}
+	}
+	return diff
+}
+
+// Watch signals changed after one of paths is written, created or
+// replaced; a path may be a glob. Directories are watched so editors that
+// save by renaming a new file over the old one are noticed too. It stops
+// with ctx.
+func Watch(ctx context.Context, paths []string, changed chan<- struct{}, errs chan<- error) error {
+	patterns := make([]string, 0, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("resolve config path: %w", err)
+		}
+		patterns = append(patterns, abs)
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+	watched := make(map[string]bool)
+	for _, pattern := range patterns {
+		dir := filepath.Dir(pattern)
+		if watched[dir] || hasMeta(dir) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			_ = watcher.Close()
+			return fmt.Errorf("watch config dir: %w", err)
+		}
+		watched[dir] = true
+	}
+	matches := func(name string) bool {
+		name = filepath.Clean(name)
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok || pattern == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		timer := time.NewTimer(watchDebounce)
+		timer.Stop()
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err := <-watcher.Errors:
+				if err != nil {
+					select {
+					case errs <- fmt.Errorf("watch config: %w", err):
+					default:
+					}
+				}
+			case event := <-watcher.Events:
+				if !matches(event.Name) {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					timer.Reset(watchDebounce)
+				}
+			case <-timer.C:
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return nil
+}
@@ -15,6 +15,7 @@ type Criteria struct {
 	Since    time.Time
 	Until    time.Time
 	Fields   map[string]string
+	Query    Node
 }
 
 func (c Criteria) Matches(event parse.StructuredEvent) bool {
@@ -35,6 +36,9 @@ func (c Criteria) Matches(event parse.StructuredEvent) bool {
 			return false
 		}
 	}
+	if c.Query != nil && !c.Query.Matches(event) {
+		return false
+	}
 	return true
 }
 
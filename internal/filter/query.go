@@ -0,0 +1,435 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-log-aggregator/internal/parse"
+)
+
+// Node is a parsed query expression.
+type Node interface {
+	Matches(event parse.StructuredEvent) bool
+	String() string
+}
+
+// ParseQuery parses a query such as
+//
+//	source:nginx AND status>=500 AND NOT path:/health*
+//
+// Terms are combined with AND, OR and NOT (upper case) and parentheses;
+// adjacent terms without an operator are ANDed. A term is either free text,
+// matched as a case-insensitive substring of the message or raw line, or a
+// field comparison:
+//
+//	field:value   equality, with * and ? wildcards; field:* tests presence
+//	field!=value  negated equality
+//	field~regex   regular expression match
+//	field>N       numeric comparison (also >=, <, <=)
+//
+// Values may be double-quoted to include spaces or operators; wildcards in
+// quoted values are literal. The message and raw fields match substrings.
+func ParseQuery(input string) (Node, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("query: unexpected %s at position %d", tok, tok.pos)
+	}
+	return node, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return strconv.Quote(t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+func lexQuery(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			closed := false
+			for i < len(input) {
+				if input[i] == '\\' && i+1 < len(input) {
+					b.WriteByte(input[i+1])
+					i += 2
+					continue
+				}
+				if input[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				b.WriteByte(input[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("query: unterminated quote at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: start})
+		case isOpStart(input, i):
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && (c == '!' || c == '>' || c == '<') {
+				op += "="
+			}
+			tokens = append(tokens, token{kind: tokenOp, value: op, pos: i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) && !isWordBreak(input, i) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: input[start:i], pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+func isOpStart(input string, i int) bool {
+	switch input[i] {
+	case ':', '=', '~', '>', '<':
+		return true
+	case '!':
+		return i+1 < len(input) && input[i+1] == '='
+	}
+	return false
+}
+
+func isWordBreak(input string, i int) bool {
+	switch input[i] {
+	case ' ', '\t', '\n', '\r', '(', ')', '"':
+		return true
+	}
+	return isOpStart(input, i)
+}
+
+type queryParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token {
+	if p.pos >= len(p.tokens) {
+		end := 0
+		if len(p.tokens) > 0 {
+			last := p.tokens[len(p.tokens)-1]
+			end = last.pos + len(last.value)
+		}
+		return token{kind: tokenEOF, pos: end}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	tok := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) keyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && tok.value == word
+}
+
+func (p *queryParser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.keyword("AND") {
+			p.next()
+		} else if tok := p.peek(); tok.kind == tokenEOF || tok.kind == tokenRParen || p.keyword("OR") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+func (p *queryParser) parseNot() (Node, error) {
+	if p.keyword("NOT") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, fmt.Errorf("query: expected ) at position %d, got %s", closing.pos, closing)
+		}
+		return node, nil
+	case tokenString:
+		return newTextNode(tok.value, true), nil
+	case tokenWord:
+		if op := p.peek(); op.kind == tokenOp {
+			p.next()
+			value := p.next()
+			if value.kind != tokenWord && value.kind != tokenString {
+				return nil, fmt.Errorf("query: expected value after %s%s at position %d", tok.value, op.value, value.pos)
+			}
+			return newFieldNode(tok.value, op.value, value.value, value.kind == tokenString)
+		}
+		return newTextNode(tok.value, false), nil
+	default:
+		return nil, fmt.Errorf("query: unexpected %s at position %d", tok, tok.pos)
+	}
+}
+
+type andNode struct{ left, right Node }
+
+func (n andNode) Matches(event parse.StructuredEvent) bool {
+	return n.left.Matches(event) && n.right.Matches(event)
+}
+
+func (n andNode) String() string {
+	return "(" + n.left.String() + " AND " + n.right.String() + ")"
+}
+
+type orNode struct{ left, right Node }
+
+func (n orNode) Matches(event parse.StructuredEvent) bool {
+	return n.left.Matches(event) || n.right.Matches(event)
+}
+
+func (n orNode) String() string {
+	return "(" + n.left.String() + " OR " + n.right.String() + ")"
+}
+
+type notNode struct{ node Node }
+
+func (n notNode) Matches(event parse.StructuredEvent) bool {
+	return !n.node.Matches(event)
+}
+
+func (n notNode) String() string {
+	return "NOT " + n.node.String()
+}
+
+// textNode matches free text against the message and raw line.
+type textNode struct {
+	text    string
+	pattern *regexp.Regexp
+}
+
+func newTextNode(text string, quoted bool) Node {
+	return textNode{text: text, pattern: valuePattern(text, quoted, false)}
+}
+
+func (n textNode) Matches(event parse.StructuredEvent) bool {
+	return n.pattern.MatchString(event.Message) || n.pattern.MatchString(event.Raw)
+}
+
+func (n textNode) String() string {
+	return strconv.Quote(n.text)
+}
+
+type fieldNode struct {
+	field   string
+	op      string
+	value   string
+	pattern *regexp.Regexp
+	number  float64
+	exists  bool
+}
+
+func newFieldNode(field, op, value string, quoted bool) (Node, error) {
+	n := fieldNode{field: field, op: op, value: value}
+	switch op {
+	case ":", "=", "!=":
+		if !quoted && value == "*" {
+			n.exists = true
+			break
+		}
+		n.pattern = valuePattern(value, quoted, !isTextField(field))
+	case "~":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("query: regex for %s: %w", field, err)
+		}
+		n.pattern = re
+	case ">", ">=", "<", "<=":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query: %s%s%s needs a number", field, op, value)
+		}
+		n.number = number
+	default:
+		return nil, fmt.Errorf("query: unknown operator %s", op)
+	}
+	return n, nil
+}
+
+func (n fieldNode) Matches(event parse.StructuredEvent) bool {
+	current, ok := FieldValue(event, n.field)
+	switch n.op {
+	case ":", "=":
+		if n.exists {
+			return ok && current != ""
+		}
+		return ok && n.pattern.MatchString(current)
+	case "!=":
+		if n.exists {
+			return !ok || current == ""
+		}
+		return !ok || !n.pattern.MatchString(current)
+	case "~":
+		return ok && n.pattern.MatchString(current)
+	}
+
+	if !ok {
+		return false
+	}
+	number, err := strconv.ParseFloat(strings.TrimSpace(current), 64)
+	if err != nil {
+		return false
+	}
+	switch n.op {
+	case ">":
+		return number > n.number
+	case ">=":
+		return number >= n.number
+	case "<":
+		return number < n.number
+	case "<=":
+		return number <= n.number
+	}
+	return false
+}
+
+func (n fieldNode) String() string {
+	return n.field + n.op + strconv.Quote(n.value)
+}
+
+// FieldValue resolves the event properties a query can name, falling back
+// to parsed fields.
+func FieldValue(event parse.StructuredEvent, key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case "source":
+		return event.SourceName, true
+	case "format":
+		return event.Format, true
+	case "severity", "level":
+		return event.Severity, true
+	case "message", "msg":
+		return event.Message, true
+	case "raw":
+		return event.Raw, true
+	}
+	value, ok := event.Fields[key]
+	return value, ok
+}
+
+func isTextField(field string) bool {
+	switch strings.ToLower(field) {
+	case "message", "msg", "raw":
+		return true
+	}
+	return false
+}
+
+// valuePattern builds a case-insensitive matcher for value, expanding *
+// and ? unless quoted. Anchored patterns must match the whole value.
+func valuePattern(value string, quoted, anchored bool) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)")
+	if anchored {
+		b.WriteString("^")
+	}
+	for _, r := range value {
+		switch {
+		case r == '*' && !quoted:
+			b.WriteString(".*")
+		case r == '?' && !quoted:
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	if anchored {
+		b.WriteString("$")
+	}
+	return regexp.MustCompile(b.String())
+}
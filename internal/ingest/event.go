@@ -1,10 +1,22 @@
 package ingest
 
-import "time"
+import (
+	"time"
+
+	"go-log-aggregator/internal/checkpoint"
+)
 
 type Event struct {
 	SourceName string
 	SourcePath string
 	Line       string
 	ReceivedAt time.Time
+
+	// Offset is the byte position just past Line in the tailed file and
+	// FileID identifies that file; both are zero for other inputs.
+	Offset int64
+	FileID checkpoint.FileID
+
+	// Peer is the remote address for events received over the network.
+	Peer string
 }
@@ -0,0 +1,298 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"go-log-aggregator/internal/checkpoint"
+	"go-log-aggregator/internal/config"
+)
+
+// globRescanInterval bounds how long a new file can go unnoticed when a
+// directory event is missed (e.g. directories created faster than we can
+// add watches for them).
+const globRescanInterval = 5 * time.Second
+
+// ExpandPaths resolves a source path into the concrete files it currently
+// names. Plain paths are returned as-is (even when missing), directories
+// expand to every file beneath them, and glob patterns (including `**`)
+// expand to their matches.
+func ExpandPaths(path string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolve source path: %w", err)
+	}
+
+	pattern, ok := globPattern(abs)
+	if !ok {
+		return []string{abs}, nil
+	}
+
+	files, _, err := scanGlob(pattern)
+	return files, err
+}
+
+// globPattern reports whether path needs discovery and returns the pattern
+// to expand. Existing directories are treated as `dir/**`.
+func globPattern(path string) (string, bool) {
+	if hasGlobMeta(path) {
+		return path, true
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, "**"), true
+	}
+	return "", false
+}
+
+func hasGlobMeta(path string) bool {
+	chars := `*?[`
+	if runtime.GOOS != "windows" {
+		chars = `*?[\`
+	}
+	return strings.ContainsAny(path, chars)
+}
+
+// splitGlob separates the static directory prefix of pattern from the
+// segments that need matching.
+func splitGlob(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	base := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if hasGlobMeta(segment) {
+			return filepath.FromSlash(strings.Join(base, "/")), segments[i:]
+		}
+		base = append(base, segment)
+	}
+	return filepath.Dir(pattern), []string{filepath.Base(pattern)}
+}
+
+func scanGlob(pattern string) ([]string, []string, error) {
+	base, segments := splitGlob(pattern)
+	if base == "" {
+		base = string(filepath.Separator)
+	}
+
+	var files []string
+	var dirs []string
+	err := filepath.WalkDir(base, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == base {
+				return err
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return nil
+		}
+		var parts []string
+		if rel != "." {
+			parts = strings.Split(filepath.ToSlash(rel), "/")
+		}
+
+		if entry.IsDir() {
+			if rel != "." && !globPrefixMatch(segments, parts) {
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, path)
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		}
+		if globMatch(segments, parts) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Strings(files)
+	return files, dirs, nil
+}
+
+func globMatch(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if globMatch(rest, name[i:]) {
+					return true
+				}
+			}
+			return len(rest) == 0
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := filepath.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// globPrefixMatch reports whether files below dir could still match pattern.
+func globPrefixMatch(pattern, dir []string) bool {
+	for i, part := range dir {
+		if i < len(pattern) && pattern[i] == "**" {
+			return true
+		}
+		if i >= len(pattern)-1 {
+			return false
+		}
+		if ok, err := filepath.Match(pattern[i], part); err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func startGlobTailer(ctx context.Context, source config.Source, pattern string, checkpoints *checkpoint.Store, out chan<- Event, errs chan<- error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("create watcher: %w", err)
+	}
+
+	files, dirs, err := scanGlob(pattern)
+	if err != nil {
+		_ = watcher.Close()
+		return fmt.Errorf("expand %s: %w", source.Path, err)
+	}
+
+	watched := make(map[string]struct{})
+	watchDirs := func(dirs []string) {
+		for _, dir := range dirs {
+			if _, ok := watched[dir]; ok {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				notifyError(errs, fmt.Errorf("watch %s: %w", dir, err))
+				continue
+			}
+			watched[dir] = struct{}{}
+		}
+	}
+	watchDirs(dirs)
+	if len(watched) == 0 {
+		_ = watcher.Close()
+		return fmt.Errorf("watch directory: no directories for %s", source.Path)
+	}
+
+	// Each file's tailer gets the events for its name from the one watcher.
+	type globFile struct {
+		ctx    context.Context
+		cancel context.CancelFunc
+		events chan fsnotify.Event
+	}
+	active := make(map[string]globFile)
+	missing := make(map[string]time.Time)
+	startFile := func(path string, startAtEnd bool) {
+		fileCtx, cancel := context.WithCancel(ctx)
+		events := make(chan fsnotify.Event, 16)
+		startFileTailer(fileCtx, source, path, startAtEnd, checkpoints, fileEvents{
+			events: events,
+			close:  func() error { return nil },
+		}, out, errs)
+		active[path] = globFile{ctx: fileCtx, cancel: cancel, events: events}
+	}
+	for _, path := range files {
+		startFile(path, true)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		ticker := time.NewTicker(globRescanInterval)
+		defer ticker.Stop()
+
+		rescan := func() {
+			files, dirs, err := scanGlob(pattern)
+			if err != nil {
+				notifyError(errs, fmt.Errorf("expand %s: %w", source.Name, err))
+				return
+			}
+			watchDirs(dirs)
+
+			current := make(map[string]struct{}, len(files))
+			for _, path := range files {
+				current[path] = struct{}{}
+				delete(missing, path)
+				if _, ok := active[path]; !ok {
+					startFile(path, false)
+				}
+			}
+			// A vanished file may just have been rotated; its tailer keeps
+			// draining it (and picks up a replacement) for a grace period.
+			for path, tailed := range active {
+				if _, ok := current[path]; ok {
+					continue
+				}
+				since, ok := missing[path]
+				if !ok {
+					missing[path] = time.Now()
+					continue
+				}
+				if time.Since(since) < rotateGrace+rotatePollInterval {
+					continue
+				}
+				tailed.cancel()
+				delete(active, path)
+				delete(missing, path)
+				checkpoints.Forget(path)
+			}
+			for dir := range watched {
+				if _, err := os.Stat(dir); err != nil {
+					_ = watcher.Remove(dir)
+					delete(watched, dir)
+				}
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				for _, tailed := range active {
+					tailed.cancel()
+				}
+				return
+			case err := <-watcher.Errors:
+				if err != nil {
+					notifyError(errs, fmt.Errorf("watcher %s: %w", source.Name, err))
+				}
+			case event := <-watcher.Events:
+				if tailed, ok := active[filepath.Clean(event.Name)]; ok {
+					select {
+					case tailed.events <- event:
+					case <-tailed.ctx.Done():
+					}
+				}
+				if event.Op&(fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+					rescan()
+				}
+			case <-ticker.C:
+				rescan()
+			}
+		}
+	}()
+
+	return nil
+}
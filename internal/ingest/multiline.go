@@ -0,0 +1,188 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+)
+
+const (
+	defaultMultilineMaxLines     = 500
+	defaultMultilineFlushTimeout = time.Second
+)
+
+type multilineRule struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	negate       bool
+	maxLines     int
+	flushTimeout time.Duration
+}
+
+type pendingEvent struct {
+	first   Event
+	last    Event
+	lines   []string
+	updated time.Time
+	rule    *multilineRule
+}
+
+// Assembler joins lines into multiline events according to each source's
+// multiline rule. Lines are grouped per file, so glob sources never mix
+// lines from different files. It is not safe for concurrent use.
+type Assembler struct {
+	rules   map[string]*multilineRule
+	pending map[string]*pendingEvent
+}
+
+func NewAssembler(sources []config.Source) (*Assembler, error) {
+	rules := make(map[string]*multilineRule)
+	var errs []error
+	for _, src := range sources {
+		if src.Multiline == nil {
+			continue
+		}
+		rule, err := compileMultiline(*src.Multiline)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %s multiline: %w", src.Name, err))
+			continue
+		}
+		rules[src.Name] = rule
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &Assembler{
+		rules:   rules,
+		pending: make(map[string]*pendingEvent),
+	}, nil
+}
+
+func compileMultiline(cfg config.Multiline) (*multilineRule, error) {
+	rule := &multilineRule{
+		negate:       cfg.Negate,
+		maxLines:     cfg.MaxLines,
+		flushTimeout: defaultMultilineFlushTimeout,
+	}
+	if rule.maxLines <= 0 {
+		rule.maxLines = defaultMultilineMaxLines
+	}
+
+	if strings.TrimSpace(cfg.StartPattern) == "" && strings.TrimSpace(cfg.ContinuationPattern) == "" {
+		return nil, fmt.Errorf("startPattern or continuationPattern is required")
+	}
+	if cfg.StartPattern != "" {
+		re, err := regexp.Compile(cfg.StartPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid startPattern: %w", err)
+		}
+		rule.start = re
+	}
+	if cfg.ContinuationPattern != "" {
+		re, err := regexp.Compile(cfg.ContinuationPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid continuationPattern: %w", err)
+		}
+		rule.continuation = re
+	}
+	if strings.TrimSpace(cfg.FlushTimeout) != "" {
+		timeout, err := time.ParseDuration(cfg.FlushTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid flushTimeout: %w", err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("flushTimeout must be positive")
+		}
+		rule.flushTimeout = timeout
+	}
+	return rule, nil
+}
+
+func (r *multilineRule) continues(line string) bool {
+	if r.start != nil && r.start.MatchString(line) != r.negate {
+		return false
+	}
+	if r.continuation != nil {
+		return r.continuation.MatchString(line) != r.negate
+	}
+	return true
+}
+
+// Add feeds one line and returns the events completed by it. Sources
+// without a multiline rule pass straight through.
+func (a *Assembler) Add(event Event) []Event {
+	if a == nil {
+		return []Event{event}
+	}
+	rule, ok := a.rules[event.SourceName]
+	if !ok {
+		return []Event{event}
+	}
+
+	var out []Event
+	current := a.pending[event.SourcePath]
+	if current != nil && rule.continues(event.Line) {
+		current.lines = append(current.lines, event.Line)
+		current.last = event
+		current.updated = event.ReceivedAt
+	} else {
+		if current != nil {
+			out = append(out, current.finish())
+		}
+		current = &pendingEvent{
+			first:   event,
+			last:    event,
+			lines:   []string{event.Line},
+			updated: event.ReceivedAt,
+			rule:    rule,
+		}
+		a.pending[event.SourcePath] = current
+	}
+
+	if len(current.lines) >= rule.maxLines {
+		out = append(out, current.finish())
+		delete(a.pending, event.SourcePath)
+	}
+	return out
+}
+
+// Expire returns pending events that have not grown within their rule's
+// flush timeout.
+func (a *Assembler) Expire(now time.Time) []Event {
+	if a == nil {
+		return nil
+	}
+	var out []Event
+	for path, current := range a.pending {
+		if now.Sub(current.updated) < current.rule.flushTimeout {
+			continue
+		}
+		out = append(out, current.finish())
+		delete(a.pending, path)
+	}
+	return out
+}
+
+// FlushAll returns every pending event regardless of age.
+func (a *Assembler) FlushAll() []Event {
+	if a == nil {
+		return nil
+	}
+	out := make([]Event, 0, len(a.pending))
+	for path, current := range a.pending {
+		out = append(out, current.finish())
+		delete(a.pending, path)
+	}
+	return out
+}
+
+func (p *pendingEvent) finish() Event {
+	event := p.last
+	event.Line = strings.Join(p.lines, "\n")
+	event.ReceivedAt = p.first.ReceivedAt
+	return event
+}
@@ -0,0 +1,21 @@
+package ingest
+
+import (
+	"context"
+
+	"go-log-aggregator/internal/checkpoint"
+	"go-log-aggregator/internal/config"
+)
+
+// StartSource starts the input matching the source type.
+func StartSource(ctx context.Context, source config.Source, checkpoints *checkpoint.Store, out chan<- Event, errs chan<- error) error {
+	switch source.Kind() {
+	case config.SourceTypeSyslog:
+		return StartSyslogListener(ctx, source, out, errs)
+	case config.SourceTypeHTTP:
+		// Pushed through the web server's ingest endpoint.
+		return nil
+	default:
+		return StartTailer(ctx, source, checkpoints, out, errs)
+	}
+}
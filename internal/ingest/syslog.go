@@ -0,0 +1,215 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-log-aggregator/internal/config"
+)
+
+const (
+	maxSyslogDatagram = 64 * 1024
+	maxSyslogFrame    = 1024 * 1024
+	// maxOctetDigits is len("1048576"), the digits of maxSyslogFrame.
+	maxOctetDigits = 7
+)
+
+// StartSyslogListener receives syslog messages on source.Address over UDP
+// datagrams and/or TCP streams. TCP frames are either newline-terminated
+// or RFC 6587 octet-counted ("LEN SP MSG"), detected per frame.
+func StartSyslogListener(ctx context.Context, source config.Source, out chan<- Event, errs chan<- error) error {
+	if out == nil {
+		return fmt.Errorf("event channel is required")
+	}
+
+	protocol := strings.ToLower(strings.TrimSpace(source.Protocol))
+	var packetConn net.PacketConn
+	var listener net.Listener
+	var err error
+
+	if protocol == "" || protocol == "udp" {
+		packetConn, err = net.ListenPacket("udp", source.Address)
+		if err != nil {
+			return fmt.Errorf("listen udp %s: %w", source.Address, err)
+		}
+	}
+	if protocol == "" || protocol == "tcp" {
+		listener, err = net.Listen("tcp", source.Address)
+		if err != nil {
+			if packetConn != nil {
+				_ = packetConn.Close()
+			}
+			return fmt.Errorf("listen tcp %s: %w", source.Address, err)
+		}
+	}
+
+	emit := func(network, peer, line string) {
+		select {
+		case <-ctx.Done():
+		case out <- Event{
+			SourceName: source.Name,
+			SourcePath: network + "://" + peer,
+			Line:       line,
+			Peer:       peer,
+			ReceivedAt: time.Now(),
+		}:
+		}
+	}
+
+	if packetConn != nil {
+		go func() {
+			<-ctx.Done()
+			_ = packetConn.Close()
+		}()
+		go serveSyslogUDP(ctx, source, packetConn, emit, errs)
+	}
+	if listener != nil {
+		go func() {
+			<-ctx.Done()
+			_ = listener.Close()
+		}()
+		go serveSyslogTCP(ctx, source, listener, emit, errs)
+	}
+	return nil
+}
+
+func serveSyslogUDP(ctx context.Context, source config.Source, conn net.PacketConn, emit func(string, string, string), errs chan<- error) {
+	buf := make([]byte, maxSyslogDatagram)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			notifyError(errs, fmt.Errorf("syslog %s udp: %w", source.Name, err))
+			continue
+		}
+		line := strings.TrimRight(string(buf[:n]), "\r\n\x00")
+		if line == "" {
+			continue
+		}
+		emit("udp", addr.String(), line)
+	}
+}
+
+func serveSyslogTCP(ctx context.Context, source config.Source, listener net.Listener, emit func(string, string, string), errs chan<- error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			notifyError(errs, fmt.Errorf("syslog %s tcp: %w", source.Name, err))
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			go func() {
+				<-connCtx.Done()
+				_ = conn.Close()
+			}()
+
+			peer := conn.RemoteAddr().String()
+			err := readSyslogFrames(bufio.NewReader(conn), func(line string) {
+				emit("tcp", peer, line)
+			})
+			if err != nil && ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				notifyError(errs, fmt.Errorf("syslog %s tcp %s: %w", source.Name, peer, err))
+			}
+		}()
+	}
+}
+
+// readSyslogFrames splits a TCP stream into messages until EOF.
+func readSyslogFrames(reader *bufio.Reader, emit func(string)) error {
+	for {
+		first, err := reader.Peek(1)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if first[0] >= '1' && first[0] <= '9' {
+			size, err := readOctetCount(reader)
+			if err != nil {
+				return err
+			}
+			frame := make([]byte, size)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return fmt.Errorf("octet-counted frame: %w", err)
+			}
+			if line := strings.TrimRight(string(frame), "\r\n"); line != "" {
+				emit(line)
+			}
+			continue
+		}
+
+		line, err := readSyslogLine(reader)
+		if line = strings.TrimRight(line, "\r\n\x00"); line != "" {
+			emit(line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// readOctetCount reads the "<size> " prefix of an octet-counted frame,
+// giving up after as many digits as maxSyslogFrame has.
+func readOctetCount(reader *bufio.Reader) (int, error) {
+	var digits []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("octet count: %w", err)
+		}
+		if b == ' ' {
+			break
+		}
+		digits = append(digits, b)
+		if b < '0' || b > '9' || len(digits) > maxOctetDigits {
+			return 0, fmt.Errorf("invalid octet count %q", digits)
+		}
+	}
+	size, err := strconv.Atoi(string(digits))
+	if err != nil || size <= 0 || size > maxSyslogFrame {
+		return 0, fmt.Errorf("invalid octet count %q", digits)
+	}
+	return size, nil
+}
+
+// readSyslogLine reads a newline-terminated frame, failing once it grows
+// past maxSyslogFrame instead of buffering the rest of it.
+func readSyslogLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxSyslogFrame {
+			return "", fmt.Errorf("frame exceeds %d bytes", maxSyslogFrame)
+		}
+		line = append(line, chunk...)
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return string(line), err
+		}
+	}
+}
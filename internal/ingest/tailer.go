@@ -14,10 +14,11 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 
+	"go-log-aggregator/internal/checkpoint"
 	"go-log-aggregator/internal/config"
 )
 
-func StartTailer(ctx context.Context, source config.Source, out chan<- Event, errs chan<- error) error {
+func StartTailer(ctx context.Context, source config.Source, checkpoints *checkpoint.Store, out chan<- Event, errs chan<- error) error {
 	if out == nil {
 		return fmt.Errorf("event channel is required")
 	}
@@ -27,68 +28,180 @@ func StartTailer(ctx context.Context, source config.Source, out chan<- Event, er
 		return fmt.Errorf("resolve source path: %w", err)
 	}
 
-	watcher, err := fsnotify.NewWatcher()
+	if pattern, ok := globPattern(sourcePath); ok {
+		return startGlobTailer(ctx, source, pattern, checkpoints, out, errs)
+	}
+	watch, err := watchDir(filepath.Dir(sourcePath))
 	if err != nil {
-		return fmt.Errorf("create watcher: %w", err)
+		return err
 	}
+	startFileTailer(ctx, source, sourcePath, true, checkpoints, watch, out, errs)
+	return nil
+}
 
-	dir := filepath.Dir(sourcePath)
+// fileEvents feeds a file tailer the events of its directory: from its own
+// watcher, or its share of a glob tailer's so a glob over many files uses
+// one inotify instance.
+type fileEvents struct {
+	events <-chan fsnotify.Event
+	errors <-chan error
+	close  func() error
+}
+
+func watchDir(dir string) (fileEvents, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fileEvents{}, fmt.Errorf("create watcher: %w", err)
+	}
 	if err := watcher.Add(dir); err != nil {
 		_ = watcher.Close()
-		return fmt.Errorf("watch directory: %w", err)
+		return fileEvents{}, fmt.Errorf("watch directory: %w", err)
 	}
+	return fileEvents{events: watcher.Events, errors: watcher.Errors, close: watcher.Close}, nil
+}
 
-	go func() {
-		defer watcher.Close()
+// rotateGrace is how long a renamed or removed file keeps being drained
+// while its writer may still hold it open, when no replacement appears.
+const rotateGrace = 5 * time.Second
 
-		var file *os.File
-		var partialLine string
-		emitLine := func(line string) {
-			select {
-			case <-ctx.Done():
-				return
-			case out <- Event{
-				SourceName: source.Name,
-				SourcePath: sourcePath,
-				Line:       line,
-				ReceivedAt: time.Now(),
-			}:
-			}
+// rotatePollInterval drains a rotated file, whose writes no longer carry
+// the watched name.
+const rotatePollInterval = time.Second
+
+func startFileTailer(ctx context.Context, source config.Source, sourcePath string, startAtEnd bool, checkpoints *checkpoint.Store, watch fileEvents, out chan<- Event, errs chan<- error) {
+	var file *os.File
+	var fileID checkpoint.FileID
+	var partialLine string
+	var offset int64
+	var rotatedAt time.Time
+	emitLine := func(line string, end int64) {
+		select {
+		case <-ctx.Done():
+			return
+		case out <- Event{
+			SourceName: source.Name,
+			SourcePath: sourcePath,
+			Line:       line,
+			Offset:     end,
+			FileID:     fileID,
+			ReceivedAt: time.Now(),
+		}:
+		}
+	}
+
+	closeFile := func() {
+		if file == nil {
+			return
 		}
+		_ = file.Close()
+		file = nil
+		rotatedAt = time.Time{}
+	}
 
-		closeFile := func() {
-			if file == nil {
-				return
+	openFile := func(startAtEnd, resume bool) error {
+		closeFile()
+
+		f, err := os.Open(sourcePath)
+		if err != nil {
+			return err
+		}
+
+		var start int64
+		if resume {
+			if saved, ok := checkpoints.Resume(sourcePath, f); ok {
+				start = saved
+				startAtEnd = false
 			}
-			_ = file.Close()
-			file = nil
 		}
 
-		openFile := func(startAtEnd bool) error {
-			closeFile()
+		if startAtEnd {
+			start, err = f.Seek(0, io.SeekEnd)
+		} else {
+			_, err = f.Seek(start, io.SeekStart)
+		}
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
 
-			f, err := os.Open(sourcePath)
-			if err != nil {
-				return err
-			}
+		id, err := checkpoint.Identify(f)
+		if err != nil {
+			notifyError(errs, fmt.Errorf("identify %s: %w", source.Name, err))
+		}
+		fileID = id
+		checkpoints.Track(sourcePath, id, start)
 
-			if startAtEnd {
-				if _, err := f.Seek(0, io.SeekEnd); err != nil {
-					_ = f.Close()
-					return err
-				}
-			} else if _, err := f.Seek(0, io.SeekStart); err != nil {
-				_ = f.Close()
-				return err
+		file = f
+		offset = start
+		partialLine = ""
+		return nil
+	}
+
+	// checkTruncated rewinds after copytruncate-style rotation: the file is
+	// now shorter than what we consumed, or its head no longer matches.
+	checkTruncated := func() {
+		info, err := file.Stat()
+		if err != nil {
+			return
+		}
+		consumed := offset + int64(len(partialLine))
+		if info.Size() >= consumed && !checkpoint.HeadChanged(file, fileID) {
+			return
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			notifyError(errs, fmt.Errorf("rewind %s: %w", source.Name, err))
+			return
+		}
+		id, err := checkpoint.Identify(file)
+		if err != nil {
+			notifyError(errs, fmt.Errorf("identify %s: %w", source.Name, err))
+		}
+		fileID = id
+		offset = 0
+		partialLine = ""
+		checkpoints.Track(sourcePath, id, 0)
+	}
+
+	readFile := func() {
+		if rotatedAt.IsZero() {
+			checkTruncated()
+		}
+		if err := readLines(file, &partialLine, &offset, emitLine); err != nil {
+			notifyError(errs, fmt.Errorf("read %s: %w", source.Name, err))
+		}
+		if fileID.FingerprintSize < checkpoint.FingerprintBytes {
+			if id, err := checkpoint.Identify(file); err == nil && id.FingerprintSize > fileID.FingerprintSize {
+				fileID = id
+				checkpoints.UpdateIdentity(sourcePath, id)
 			}
+		}
+	}
 
-			file = f
+	// finishRotated drains the rotated file to EOF and emits any trailing
+	// unterminated line before letting go of it.
+	finishRotated := func() {
+		readFile()
+		if partialLine != "" {
+			offset += int64(len(partialLine))
+			line := strings.TrimRight(partialLine, "\r")
 			partialLine = ""
-			return nil
+			emitLine(line, offset)
 		}
+		closeFile()
+	}
 
-		if err := openFile(true); err != nil && !errors.Is(err, os.ErrNotExist) {
-			notifyError(errs, fmt.Errorf("open %s: %w", source.Name, err))
+	if err := openFile(startAtEnd, true); err != nil && !errors.Is(err, os.ErrNotExist) {
+		notifyError(errs, fmt.Errorf("open %s: %w", source.Name, err))
+	}
+
+	go func() {
+		defer watch.close()
+
+		ticker := time.NewTicker(rotatePollInterval)
+		defer ticker.Stop()
+
+		if file != nil {
+			readFile()
 		}
 
 		for {
@@ -96,33 +209,49 @@ func StartTailer(ctx context.Context, source config.Source, out chan<- Event, er
 			case <-ctx.Done():
 				closeFile()
 				return
-			case err := <-watcher.Errors:
+			case err := <-watch.errors:
 				if err != nil {
 					notifyError(errs, fmt.Errorf("watcher %s: %w", source.Name, err))
 				}
-			case event := <-watcher.Events:
+			case <-ticker.C:
+				if file == nil || rotatedAt.IsZero() {
+					continue
+				}
+				if time.Since(rotatedAt) >= rotateGrace {
+					finishRotated()
+				} else {
+					readFile()
+				}
+			case event := <-watch.events:
 				if !sameFile(event.Name, sourcePath) {
 					continue
 				}
 
 				if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
-					closeFile()
+					if file != nil && rotatedAt.IsZero() {
+						readFile()
+						rotatedAt = time.Now()
+					}
 					continue
 				}
 
-				if event.Op&fsnotify.Create != 0 && file == nil {
-					if err := openFile(false); err != nil && !errors.Is(err, os.ErrNotExist) {
+				if event.Op&fsnotify.Create != 0 && (file == nil || !rotatedAt.IsZero()) {
+					if file != nil {
+						finishRotated()
+					}
+					if err := openFile(false, false); err != nil && !errors.Is(err, os.ErrNotExist) {
 						notifyError(errs, fmt.Errorf("open %s: %w", source.Name, err))
 					} else if file != nil {
-						if err := readAvailable(file, &partialLine, emitLine); err != nil {
-							notifyError(errs, fmt.Errorf("read %s: %w", source.Name, err))
-						}
+						readFile()
 					}
 				}
 
 				if event.Op&fsnotify.Write != 0 {
+					if file != nil && !rotatedAt.IsZero() {
+						finishRotated()
+					}
 					if file == nil {
-						if err := openFile(false); err != nil {
+						if err := openFile(false, false); err != nil {
 							if !errors.Is(err, os.ErrNotExist) {
 								notifyError(errs, fmt.Errorf("open %s: %w", source.Name, err))
 							}
@@ -130,27 +259,34 @@ func StartTailer(ctx context.Context, source config.Source, out chan<- Event, er
 						}
 					}
 
-					if err := readAvailable(file, &partialLine, emitLine); err != nil {
-						notifyError(errs, fmt.Errorf("read %s: %w", source.Name, err))
-					}
+					readFile()
 				}
 			}
 		}
 	}()
-
-	return nil
 }
 
 func readAvailable(file *os.File, partialLine *string, emit func(string)) error {
+	var offset int64
+	return readLines(file, partialLine, &offset, func(line string, _ int64) {
+		emit(line)
+	})
+}
+
+// readLines emits every complete line available in file. offset tracks the
+// byte position just past the last complete line, which is what a
+// checkpoint may safely record.
+func readLines(file *os.File, partialLine *string, offset *int64, emit func(string, int64)) error {
 	reader := bufio.NewReader(file)
 	for {
 		chunk, err := reader.ReadString('\n')
 		if len(chunk) > 0 {
 			if strings.HasSuffix(chunk, "\n") {
+				*offset += int64(len(*partialLine) + len(chunk))
 				line := strings.TrimRight(chunk, "\r\n")
 				line = *partialLine + line
 				*partialLine = ""
-				emit(line)
+				emit(line, *offset)
 			} else {
 				*partialLine += chunk
 			}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+)
+
+const defaultSubject = `[{{with .State}}{{upper .}}{{else}}ALERT{{end}}] {{.RuleName}}{{with .Group}} ({{.}}){{end}}`
+
+// Email sends a plain-text message through an SMTP relay. Authentication
+// is used when a user is configured.
+type Email struct {
+	addr    string
+	host    string
+	auth    smtp.Auth
+	timeout time.Duration
+	from    string
+	to      []string
+	subject *template.Template
+	body    *template.Template
+}
+
+func newEmail(cfg config.Notifier, body *template.Template) (*Email, error) {
+	if strings.TrimSpace(cfg.SMTPAddr) == "" {
+		return nil, fmt.Errorf("smtpAddr is required")
+	}
+	host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+	if err != nil {
+		return nil, fmt.Errorf("smtpAddr: %w", err)
+	}
+	if strings.TrimSpace(cfg.From) == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("from and to are required")
+	}
+	timeout, err := parseDuration("timeout", cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	subjectText := cfg.Subject
+	if strings.TrimSpace(subjectText) == "" {
+		subjectText = defaultSubject
+	}
+	subject, err := parseTemplate("subject", subjectText)
+	if err != nil {
+		return nil, err
+	}
+
+	email := &Email{
+		addr:    cfg.SMTPAddr,
+		host:    host,
+		timeout: timeout,
+		from:    cfg.From,
+		to:      cfg.To,
+		subject: subject,
+		body:    body,
+	}
+	if cfg.SMTPUser != "" {
+		email.auth = smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, host)
+	}
+	return email, nil
+}
+
+func (e *Email) Notify(ctx context.Context, match alert.Match) error {
+	subject, err := render(e.subject, match)
+	if err != nil {
+		return err
+	}
+	body, err := render(e.body, match)
+	if err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	if err := e.send(ctx, []byte(msg.String())); err != nil {
+		return fmt.Errorf("send mail: %w", err)
+	}
+	return nil
+}
+
+// send delivers msg the way smtp.SendMail does, but over a connection
+// whose deadline follows ctx so a stalled relay can't hold a worker.
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	dialer := net.Dialer{Timeout: e.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Closing the connection unblocks the client if ctx ends first.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.host}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if err := client.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
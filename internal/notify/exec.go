@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+)
+
+// Exec runs a command per alert. The rendered template (or a JSON Payload)
+// is written to its stdin, and ALERT_* variables describe the alert.
+type Exec struct {
+	command []string
+	timeout time.Duration
+	body    *template.Template
+}
+
+func newExec(cfg config.Notifier, body *template.Template) (*Exec, error) {
+	if len(cfg.Command) == 0 || strings.TrimSpace(cfg.Command[0]) == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	timeout, err := parseDuration("timeout", cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Exec{command: cfg.Command, timeout: timeout, body: body}, nil
+}
+
+func (e *Exec) Notify(ctx context.Context, match alert.Match) error {
+	var input string
+	if e.body != nil {
+		text, err := render(e.body, match)
+		if err != nil {
+			return err
+		}
+		input = text
+	} else {
+		data, err := json.Marshal(NewPayload(match))
+		if err != nil {
+			return err
+		}
+		input = string(data) + "\n"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		"ALERT_RULE="+match.RuleName,
+		"ALERT_STATE="+match.State,
+		"ALERT_GROUP="+match.Group,
+		"ALERT_SOURCE="+match.Event.SourceName,
+		"ALERT_SEVERITY="+match.Event.Severity,
+		"ALERT_MESSAGE="+match.Event.Message,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("exec %s: %w: %s", e.command[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
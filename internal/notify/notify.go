@@ -0,0 +1,295 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+)
+
+const (
+	TypeWebhook = "webhook"
+	TypeSlack   = "slack"
+	TypeEmail   = "email"
+	TypeExec    = "exec"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	queueSize      = 256
+	maxInFlight    = 8
+	// drainTimeout bounds how long Run keeps delivering queued alerts
+	// once its context is done.
+	drainTimeout = 10 * time.Second
+)
+
+// defaultText is the message body used when a notifier has no template.
+const defaultText = `{{.RuleName}}{{with .State}} {{.}}{{end}}{{with .Group}} group={{.}}{{end}}` +
+	`{{if .Window}} value={{.Value}} threshold={{.Threshold}} window={{.Window}}{{end}}` +
+	`{{if gt .Count 1}} count={{.Count}}{{end}}{{with .Suppressed}} suppressed={{.}}{{end}}` +
+	`{{with .Event.SourceName}} source={{.}}{{end}}{{with .Event.Message}} message={{.}}{{end}}`
+
+// Notifier delivers one alert to an external channel.
+type Notifier interface {
+	Notify(ctx context.Context, match alert.Match) error
+}
+
+// New builds the notifier described by cfg.
+func New(cfg config.Notifier) (Notifier, error) {
+	body, err := parseTemplate("template", cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case TypeWebhook:
+		return newWebhook(cfg, body)
+	case TypeSlack:
+		if body == nil {
+			body = template.Must(parseTemplate("template", defaultText))
+		}
+		return newSlack(cfg, body)
+	case TypeEmail:
+		if body == nil {
+			body = template.Must(parseTemplate("template", defaultText))
+		}
+		return newEmail(cfg, body)
+	case TypeExec:
+		return newExec(cfg, body)
+	default:
+		return nil, fmt.Errorf("type must be webhook, slack, email or exec")
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func render(tmpl *template.Template, match alert.Match) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, match); err != nil {
+		return "", fmt.Errorf("render %s: %w", tmpl.Name(), err)
+	}
+	return buf.String(), nil
+}
+
+// Payload is the default JSON body for webhook and exec notifiers.
+// Timestamp is left out when the event has none, as for a resolved alert.
+type Payload struct {
+	Rule      string            `json:"rule"`
+	State     string            `json:"state,omitempty"`
+	Group     string            `json:"group,omitempty"`
+	Count     int               `json:"count,omitempty"`
+	Value     float64           `json:"value,omitempty"`
+	Threshold float64           `json:"threshold,omitempty"`
+	Window    string            `json:"window,omitempty"`
+	Source    string            `json:"source,omitempty"`
+	Severity  string            `json:"severity,omitempty"`
+	Message   string            `json:"message,omitempty"`
+	Raw       string            `json:"raw,omitempty"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	// Samples holds the raw lines of a grouped notification.
+	Samples    []string `json:"samples,omitempty"`
+	Suppressed int      `json:"suppressed,omitempty"`
+}
+
+func NewPayload(match alert.Match) Payload {
+	payload := Payload{
+		Rule:      match.RuleName,
+		State:     match.State,
+		Group:     match.Group,
+		Count:     match.Count,
+		Value:     match.Value,
+		Threshold: match.Threshold,
+		Source:    match.Event.SourceName,
+		Severity:  match.Event.Severity,
+		Message:   match.Event.Message,
+		Raw:       match.Event.Raw,
+		Fields:    match.Event.Fields,
+	}
+	if ts := match.Event.Timestamp; !ts.IsZero() {
+		payload.Timestamp = &ts
+	}
+	payload.Suppressed = match.Suppressed
+	if match.Window > 0 {
+		payload.Window = match.Window.String()
+	}
+	if len(match.Samples) > 1 {
+		for _, sample := range match.Samples {
+			payload.Samples = append(payload.Samples, sample.Raw)
+		}
+	}
+	return payload
+}
+
+func parseDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return fallback, nil
+	}
+	dur, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || dur <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", name)
+	}
+	return dur, nil
+}
+
+// Dispatcher routes alert matches to the notifiers configured on their
+// rule. Delivery runs in the background so a slow channel never stalls
+// ingestion.
+type Dispatcher struct {
+	mu     sync.RWMutex
+	routes map[string][]Notifier
+	queue  chan alert.Match
+}
+
+func NewDispatcher(rules []config.AlertRule) (*Dispatcher, error) {
+	routes, err := buildRoutes(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{routes: routes, queue: make(chan alert.Match, queueSize)}, nil
+}
+
+// Reload replaces the routes with those of rules. On error the current
+// routes are kept. Queued matches are delivered with the new routes.
+func (d *Dispatcher) Reload(rules []config.AlertRule) error {
+	if d == nil {
+		return nil
+	}
+	routes, err := buildRoutes(rules)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.routes = routes
+	d.mu.Unlock()
+	return nil
+}
+
+func buildRoutes(rules []config.AlertRule) (map[string][]Notifier, error) {
+	routes := make(map[string][]Notifier)
+	var errs []error
+	for _, rule := range rules {
+		for i, cfg := range rule.Notify {
+			notifier, err := New(cfg)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("alert %s: notify[%d]: %w", rule.Name, i, err))
+				continue
+			}
+			routes[rule.Name] = append(routes[rule.Name], notifier)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return routes, nil
+}
+
+func (d *Dispatcher) route(rule string) []Notifier {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.routes[rule]
+}
+
+// Send queues match for delivery. It never blocks; when the queue is full
+// the notification is dropped and false is returned.
+func (d *Dispatcher) Send(match alert.Match) bool {
+	if d == nil || len(d.route(match.RuleName)) == 0 {
+		return false
+	}
+	select {
+	case d.queue <- match:
+		return true
+	default:
+		log.Printf("notify: queue full, dropping %s", match.RuleName)
+		return false
+	}
+}
+
+// Run delivers queued matches until ctx is done, then delivers what is
+// still queued, waiting up to drainTimeout, and returns once every
+// delivery has finished.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d == nil {
+		return
+	}
+
+	// Deliveries outlive ctx until the drain is over.
+	sendCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxInFlight)
+	deliver := func(match alert.Match) bool {
+		for _, notifier := range d.route(match.RuleName) {
+			select {
+			case <-sendCtx.Done():
+				return false
+			case slots <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(notifier Notifier) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if err := notifier.Notify(sendCtx, match); err != nil {
+					log.Printf("notify %s: %v", match.RuleName, err)
+				}
+			}(notifier)
+		}
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer := time.AfterFunc(drainTimeout, cancel)
+			defer timer.Stop()
+			d.drain(deliver)
+			wg.Wait()
+			return
+		case match := <-d.queue:
+			deliver(match)
+		}
+	}
+}
+
+// drain hands every queued match to deliver until the queue is empty or
+// deliver gives up, logging how many were dropped.
+func (d *Dispatcher) drain(deliver func(alert.Match) bool) {
+	for {
+		select {
+		case match := <-d.queue:
+			if !deliver(match) {
+				log.Printf("notify: drain timed out, dropping %d queued notification(s)", len(d.queue)+1)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
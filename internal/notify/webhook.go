@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// poster sends JSON bodies, retrying network errors, 429s and 5xx
+// responses with exponential backoff.
+type poster struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+	retries int
+	backoff time.Duration
+}
+
+func newPoster(cfg config.Notifier) (poster, error) {
+	if strings.TrimSpace(cfg.URL) == "" {
+		return poster{}, fmt.Errorf("url is required")
+	}
+	timeout, err := parseDuration("timeout", cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return poster{}, err
+	}
+	backoff, err := parseDuration("backoff", cfg.Backoff, defaultBackoff)
+	if err != nil {
+		return poster{}, err
+	}
+	retries := defaultRetries
+	if cfg.Retries != nil {
+		retries = *cfg.Retries
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	return poster{
+		client:  &http.Client{Timeout: timeout},
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		retries: retries,
+		backoff: backoff,
+	}, nil
+}
+
+func (p poster) post(ctx context.Context, body []byte) error {
+	backoff := p.backoff
+	var lastErr error
+	for attempt := 0; attempt <= p.retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+
+		retry, err := p.attempt(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+func (p poster) attempt(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("post %s: %s", p.url, resp.Status)
+}
+
+// Webhook POSTs the alert as JSON: the rendered template when one is set,
+// otherwise a Payload.
+type Webhook struct {
+	poster poster
+	body   *template.Template
+}
+
+func newWebhook(cfg config.Notifier, body *template.Template) (*Webhook, error) {
+	p, err := newPoster(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Webhook{poster: p, body: body}, nil
+}
+
+func (w *Webhook) Notify(ctx context.Context, match alert.Match) error {
+	var data []byte
+	if w.body != nil {
+		text, err := render(w.body, match)
+		if err != nil {
+			return err
+		}
+		data = []byte(text)
+	} else {
+		var err error
+		if data, err = json.Marshal(NewPayload(match)); err != nil {
+			return err
+		}
+	}
+	return w.poster.post(ctx, data)
+}
+
+// Slack posts the rendered text to a Slack or Mattermost incoming webhook.
+type Slack struct {
+	poster   poster
+	text     *template.Template
+	channel  string
+	username string
+}
+
+func newSlack(cfg config.Notifier, text *template.Template) (*Slack, error) {
+	p, err := newPoster(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Slack{poster: p, text: text, channel: cfg.Channel, username: cfg.Username}, nil
+}
+
+func (s *Slack) Notify(ctx context.Context, match alert.Match) error {
+	text, err := render(s.text, match)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(struct {
+		Text     string `json:"text"`
+		Channel  string `json:"channel,omitempty"`
+		Username string `json:"username,omitempty"`
+	}{Text: text, Channel: s.channel, Username: s.username})
+	if err != nil {
+		return err
+	}
+	return s.poster.post(ctx, data)
+}
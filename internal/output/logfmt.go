@@ -0,0 +1,68 @@
+package output
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/parse"
+)
+
+// Logfmt renders events as key=value pairs: time, level, source and msg
+// first, then the event's fields in key order.
+type Logfmt struct{}
+
+func (Logfmt) Format(event parse.StructuredEvent) ([]byte, error) {
+	var b strings.Builder
+	if ts := eventTime(event); !ts.IsZero() {
+		writePair(&b, "time", ts.Format(time.RFC3339Nano))
+	}
+	if event.Severity != "" {
+		writePair(&b, "level", event.Severity)
+	}
+	writePair(&b, "source", event.SourceName)
+	message := event.Message
+	if message == "" {
+		message = event.Raw
+	}
+	writePair(&b, "msg", message)
+	for _, key := range sortedKeys(event.Fields) {
+		writePair(&b, key, event.Fields[key])
+	}
+	return []byte(b.String()), nil
+}
+
+func writePair(b *strings.Builder, key, value string) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	if needsQuote(value) {
+		b.WriteString(strconv.Quote(value))
+	} else {
+		b.WriteString(value)
+	}
+}
+
+func needsQuote(value string) bool {
+	if value == "" {
+		return true
+	}
+	for _, r := range value {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
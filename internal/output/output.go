@@ -0,0 +1,132 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-log-aggregator/internal/parse"
+)
+
+const (
+	ModeJSON     = "json"
+	ModeLogfmt   = "logfmt"
+	ModeText     = "text"
+	ModeTemplate = "template"
+	ModeNone     = "none"
+)
+
+// Formatter renders one event as a single line, without the trailing
+// newline.
+type Formatter interface {
+	Format(event parse.StructuredEvent) ([]byte, error)
+}
+
+type Options struct {
+	// Template is the Go template used by ModeTemplate.
+	Template string
+	// Color enables ANSI colors in ModeText.
+	Color bool
+	// SourceWidth is the initial width of the source column in ModeText,
+	// typically the longest configured source name.
+	SourceWidth int
+}
+
+// New returns the formatter for mode. ModeNone returns a nil Formatter.
+func New(mode string, opts Options) (Formatter, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", ModeJSON:
+		return JSON{}, nil
+	case ModeLogfmt:
+		return Logfmt{}, nil
+	case ModeText:
+		return &Text{Color: opts.Color, sourceWidth: min(opts.SourceWidth, maxSourceWidth)}, nil
+	case ModeTemplate:
+		return NewTemplate(opts.Template)
+	case ModeNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("output must be json, logfmt, text, template or none")
+	}
+}
+
+// eventTime is the event's own timestamp, or when it was received.
+func eventTime(event parse.StructuredEvent) time.Time {
+	if !event.Timestamp.IsZero() {
+		return event.Timestamp
+	}
+	return event.ReceivedAt
+}
+
+// Record is the JSON form of an event.
+type Record struct {
+	Timestamp  string            `json:"timestamp,omitempty"`
+	ReceivedAt string            `json:"received_at,omitempty"`
+	Severity   string            `json:"severity,omitempty"`
+	Message    string            `json:"message,omitempty"`
+	Source     string            `json:"source"`
+	Format     string            `json:"format,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Raw        string            `json:"raw,omitempty"`
+}
+
+func NewRecord(event parse.StructuredEvent) Record {
+	record := Record{
+		Severity: event.Severity,
+		Message:  event.Message,
+		Source:   event.SourceName,
+		Format:   event.Format,
+		Fields:   event.Fields,
+		Raw:      event.Raw,
+	}
+	if !event.ReceivedAt.IsZero() {
+		record.ReceivedAt = event.ReceivedAt.Format(time.RFC3339)
+	}
+	if ts := eventTime(event); !ts.IsZero() {
+		record.Timestamp = ts.Format(time.RFC3339)
+	}
+	return record
+}
+
+// JSON renders events as Record objects.
+type JSON struct{}
+
+func (JSON) Format(event parse.StructuredEvent) ([]byte, error) {
+	return json.Marshal(NewRecord(event))
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+}
+
+// Template renders events with a user template over parse.StructuredEvent
+// (.SourceName, .Severity, .Message, .Fields, .Timestamp...).
+type Template struct {
+	tmpl *template.Template
+}
+
+func NewTemplate(text string) (*Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("template is required")
+	}
+	tmpl, err := template.New("output").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+func (t *Template) Format(event parse.StructuredEvent) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("render template: %w", err)
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,102 @@
+package output
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go-log-aggregator/internal/parse"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiPurple = "\x1b[35m"
+	ansiCyan   = "\x1b[36m"
+
+	// maxSourceWidth caps how wide the source column grows.
+	maxSourceWidth = 24
+)
+
+// Text renders events for reading in a terminal: time, severity, source
+// and message in aligned columns, followed by the fields. With Color the
+// severity is colored.
+type Text struct {
+	Color       bool
+	sourceWidth int
+}
+
+func (t *Text) Format(event parse.StructuredEvent) ([]byte, error) {
+	var b strings.Builder
+
+	ts := "-"
+	if eventTime := eventTime(event); !eventTime.IsZero() {
+		ts = eventTime.Local().Format("2006-01-02 15:04:05.000")
+	}
+	t.paint(&b, ansiDim, ts)
+	b.WriteByte(' ')
+
+	severity := strings.ToUpper(event.Severity)
+	if severity == "" {
+		severity = "-"
+	}
+	t.paint(&b, severityColor(event.Severity), pad(severity, 8))
+	b.WriteByte(' ')
+
+	if width := utf8.RuneCountInString(event.SourceName); width > t.sourceWidth {
+		t.sourceWidth = min(width, maxSourceWidth)
+	}
+	t.paint(&b, ansiCyan, pad(event.SourceName, t.sourceWidth))
+	b.WriteByte(' ')
+
+	message := event.Message
+	if message == "" {
+		message = event.Raw
+	}
+	b.WriteString(message)
+
+	for _, key := range sortedKeys(event.Fields) {
+		b.WriteByte(' ')
+		t.paint(&b, ansiDim, key+"=")
+		value := event.Fields[key]
+		if needsQuote(value) {
+			value = `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+		}
+		b.WriteString(value)
+	}
+	return []byte(b.String()), nil
+}
+
+func (t *Text) paint(b *strings.Builder, color, text string) {
+	if !t.Color || color == "" {
+		b.WriteString(text)
+		return
+	}
+	b.WriteString(color)
+	b.WriteString(text)
+	b.WriteString(ansiReset)
+}
+
+func severityColor(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical", "fatal", "panic":
+		return ansiPurple
+	case "error":
+		return ansiRed
+	case "warn", "warning":
+		return ansiYellow
+	case "info":
+		return ansiBlue
+	default:
+		return ansiDim
+	}
+}
+
+func pad(value string, width int) string {
+	if n := utf8.RuneCountInString(value); n < width {
+		return value + strings.Repeat(" ", width-n)
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/ingest"
+)
+
+type regexFormat struct {
+	name            string
+	re              *regexp.Regexp
+	timestampField  string
+	timestampLayout string
+	severityField   string
+	messageField    string
+}
+
+// grokConventions maps conventional grok capture names onto event fields
+// when a grok format does not configure them explicitly.
+var grokConventions = struct {
+	timestamp []string
+	severity  []string
+	message   []string
+}{
+	timestamp: []string{"timestamp", "time", "ts"},
+	severity:  []string{"level", "loglevel", "severity"},
+	message:   []string{"message", "msg"},
+}
+
+func compileFormat(engine *Grok, format config.Format) (*regexFormat, error) {
+	if !strings.EqualFold(strings.TrimSpace(format.Type), "grok") {
+		re, err := regexp.Compile(format.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("format %s: invalid pattern: %w", format.Name, err)
+		}
+		return newRegexFormat(format, re)
+	}
+
+	re, err := engine.Compile(format.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", format.Name, err)
+	}
+	groups := re.SubexpNames()
+	if format.TimestampField == "" {
+		format.TimestampField = firstGroup(groups, grokConventions.timestamp)
+	}
+	if format.SeverityField == "" {
+		format.SeverityField = firstGroup(groups, grokConventions.severity)
+	}
+	if format.MessageField == "" {
+		format.MessageField = firstGroup(groups, grokConventions.message)
+	}
+	return newRegexFormat(format, re)
+}
+
+func firstGroup(groups []string, candidates []string) string {
+	for _, candidate := range candidates {
+		for _, group := range groups {
+			if group == candidate {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+func newRegexFormat(format config.Format, re *regexp.Regexp) (*regexFormat, error) {
+	groups := make(map[string]struct{})
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			groups[name] = struct{}{}
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("format %s: pattern has no named groups", format.Name)
+	}
+	for _, field := range []string{format.TimestampField, format.SeverityField, format.MessageField} {
+		if field == "" {
+			continue
+		}
+		if _, ok := groups[field]; !ok {
+			return nil, fmt.Errorf("format %s: group %q not found in pattern", format.Name, field)
+		}
+	}
+
+	return &regexFormat{
+		name:            strings.ToLower(strings.TrimSpace(format.Name)),
+		re:              re,
+		timestampField:  format.TimestampField,
+		timestampLayout: format.TimestampLayout,
+		severityField:   format.SeverityField,
+		messageField:    format.MessageField,
+	}, nil
+}
+
+func (f *regexFormat) parse(event ingest.Event) (StructuredEvent, error) {
+	matches := f.re.FindStringSubmatch(event.Line)
+	if matches == nil {
+		return StructuredEvent{}, fmt.Errorf("%s parse: no match", f.name)
+	}
+
+	values := make(map[string]string, len(matches))
+	for i, name := range f.re.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		// Names may repeat across alternatives; keep the first non-empty capture.
+		if values[name] == "" {
+			values[name] = matches[i]
+		}
+	}
+
+	var timestamp time.Time
+	if f.timestampField != "" {
+		timestamp = parseLayoutTimestamp(values[f.timestampField], f.timestampLayout)
+	}
+
+	severity := "unknown"
+	if f.severityField != "" {
+		severity = normalizeSeverity(values[f.severityField])
+	}
+
+	message := event.Line
+	if f.messageField != "" && values[f.messageField] != "" {
+		message = values[f.messageField]
+	}
+
+	fields := make(map[string]string, len(values))
+	for name, value := range values {
+		if name == f.timestampField || name == f.severityField || name == f.messageField {
+			continue
+		}
+		fields[name] = value
+	}
+
+	return StructuredEvent{
+		SourceName: event.SourceName,
+		SourcePath: event.SourcePath,
+		Format:     f.name,
+		Timestamp:  timestamp,
+		ReceivedAt: event.ReceivedAt,
+		Severity:   severity,
+		Message:    message,
+		Fields:     fields,
+		Raw:        event.Line,
+	}, nil
+}
+
+// commonTimestampLayouts are tried in order when a format sets no layout.
+var commonTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.Stamp,
+}
+
+// parseLayoutTimestamp parses value with a Go time layout. An empty layout
+// tries RFC 3339 and other common layouts; "unix" and "unixms" accept
+// epoch seconds/milliseconds.
+func parseLayoutTimestamp(value, layout string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	switch strings.ToLower(layout) {
+	case "":
+		for _, candidate := range commonTimestampLayouts {
+			if ts, err := time.Parse(candidate, value); err == nil {
+				if ts.Year() == 0 {
+					ts = ts.AddDate(time.Now().Year(), 0, 0)
+				}
+				return ts
+			}
+		}
+		return time.Time{}
+	case "unix":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			sec := int64(f)
+			return time.Unix(sec, int64((f-float64(sec))*1e9))
+		}
+		return time.Time{}
+	case "unixms":
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.UnixMilli(ms)
+		}
+		return time.Time{}
+	}
+
+	ts, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return ts
+}
@@ -0,0 +1,206 @@
+package parse
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// grokBuiltins is a subset of the Logstash core patterns, rewritten for
+// RE2 (no lookarounds or atomic groups).
+var grokBuiltins = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"EMAILLOCALPART":    `[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(?:\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*`,
+	"EMAILADDRESS":      `%{EMAILLOCALPART}@%{HOSTNAME}`,
+	"HTTPDUSER":         `(?:%{EMAILADDRESS}|%{USER})`,
+	"INT":               `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `(?:%{BASE10NUM})`,
+	"BASE16NUM":         `(?:(?:0[xX])?[0-9A-Fa-f]+)`,
+	"POSINT":            `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":         `\b(?:[0-9]+)\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"CISCOMAC":          `(?:(?:[A-Fa-f0-9]{4}\.){2}[A-Fa-f0-9]{4})`,
+	"WINDOWSMAC":        `(?:(?:[A-Fa-f0-9]{2}-){5}[A-Fa-f0-9]{2})`,
+	"COMMONMAC":         `(?:(?:[A-Fa-f0-9]{2}:){5}[A-Fa-f0-9]{2})`,
+	"MAC":               `(?:%{CISCOMAC}|%{WINDOWSMAC}|%{COMMONMAC})`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])`,
+	"IPV6":              `(?:(?:[0-9A-Fa-f]{0,4}:){2,7}(?:%{IPV4}|[0-9A-Fa-f]{1,4})?(?:%[0-9A-Za-z.]+)?)`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"UNIXPATH":          `(?:/[\w_%!$@:.,+~-]*)+`,
+	"WINPATH":           `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"PATH":              `(?:%{UNIXPATH}|%{WINPATH})`,
+	"URIPROTO":          `[A-Za-z][A-Za-z0-9+\-.]*`,
+	"URIHOST":           `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":               `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATHPARAM})?`,
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHNUM2":         `(?:0[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9])`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"DATE":              `(?:%{DATE_US}|%{DATE_EU})`,
+	"DATESTAMP":         `%{DATE}[- ]%{TIME}`,
+	"TZ":                `(?:[APMCE][SD]T|UTC)`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"ISO8601_SECOND":    `%{SECOND}`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":        `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"SYSLOGHOST":        `%{IPORHOST}`,
+	"SYSLOGFACILITY":    `<%{NONNEGINT:facility}.%{NONNEGINT:priority}>`,
+	"SYSLOGBASE":        `%{SYSLOGTIMESTAMP:timestamp} (?:%{SYSLOGFACILITY} )?%{SYSLOGHOST:logsource} %{SYSLOGPROG}:`,
+	"SYSLOGLINE":        `%{SYSLOGBASE} %{GREEDYDATA:message}`,
+	"LOGLEVEL":          `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo(?:rmation)?|INFO(?:RMATION)?|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Ee]merg(?:ency)?|EMERG(?:ENCY)?)`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{HTTPDUSER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+var grokFieldSanitizer = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Grok expands Logstash-style %{PATTERN:field} expressions into Go regexps.
+type Grok struct {
+	patterns map[string]string
+}
+
+// NewGrok returns an engine preloaded with the built-in pattern library.
+func NewGrok() *Grok {
+	patterns := make(map[string]string, len(grokBuiltins))
+	for name, def := range grokBuiltins {
+		patterns[name] = def
+	}
+	return &Grok{patterns: patterns}
+}
+
+func (g *Grok) AddPattern(name, definition string) {
+	g.patterns[name] = definition
+}
+
+// LoadPatternFile reads a Logstash pattern file: one "NAME definition" per
+// line, blank lines and # comments ignored.
+func (g *Grok) LoadPatternFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("grok patterns: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, def, ok := strings.Cut(line, " ")
+		def = strings.TrimSpace(def)
+		if !ok || def == "" {
+			return fmt.Errorf("grok patterns %s:%d: expected NAME definition", path, lineNo)
+		}
+		g.AddPattern(name, def)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("grok patterns: %w", err)
+	}
+	return nil
+}
+
+// Names lists the known pattern names.
+func (g *Grok) Names() []string {
+	names := make([]string, 0, len(g.patterns))
+	for name := range g.patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Compile expands pattern and compiles it. %{NAME:field} becomes a named
+// group; an optional trailing type (e.g. :int) is accepted and ignored,
+// as all field values are strings.
+func (g *Grok) Compile(pattern string) (*regexp.Regexp, error) {
+	expanded, err := g.expand(pattern, nil)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("grok compile: %w", err)
+	}
+	return re, nil
+}
+
+func (g *Grok) expand(pattern string, stack []string) (string, error) {
+	var expandErr error
+	out := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if expandErr != nil {
+			return ""
+		}
+		parts := grokReference.FindStringSubmatch(ref)
+		name, field := parts[1], parts[2]
+
+		def, ok := g.patterns[name]
+		if !ok {
+			expandErr = fmt.Errorf("grok: unknown pattern %s", name)
+			return ""
+		}
+		for _, seen := range stack {
+			if seen == name {
+				expandErr = fmt.Errorf("grok: recursive pattern %s", name)
+				return ""
+			}
+		}
+
+		inner, err := g.expand(def, append(stack, name))
+		if err != nil {
+			expandErr = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		return "(?P<" + grokFieldName(field) + ">" + inner + ")"
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return out, nil
+}
+
+// grokFieldName turns Logstash field references such as [http][status]
+// into valid group names (http_status).
+func grokFieldName(field string) string {
+	name := strings.Trim(grokFieldSanitizer.ReplaceAllString(field, "_"), "_")
+	if name == "" {
+		return "field"
+	}
+	return name
+}
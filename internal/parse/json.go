@@ -21,7 +21,7 @@ func parseJSON(event ingest.Event) (StructuredEvent, error) {
 
 	fields := make(map[string]string, len(payload))
 	for key, value := range payload {
-		if key == "timestamp" || key == "time" || key == "ts" || key == "msg" || key == "message" || key == "level" || key == "severity" {
+		if isReservedKey(key) {
 			continue
 		}
 		fields[key] = fmt.Sprint(value)
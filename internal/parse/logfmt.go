@@ -0,0 +1,124 @@
+package parse
+
+import (
+	"fmt"
+	"strconv"
+
+	"go-log-aggregator/internal/ingest"
+)
+
+func parseLogfmt(event ingest.Event) (StructuredEvent, error) {
+	pairs, err := decodeLogfmt(event.Line)
+	if err != nil {
+		return StructuredEvent{}, fmt.Errorf("logfmt parse: %w", err)
+	}
+
+	payload := make(map[string]interface{}, len(pairs))
+	for key, value := range pairs {
+		payload[key] = value
+	}
+
+	timestamp := extractTimestamp(payload)
+	message := extractString(payload, "msg", "message")
+	severity := normalizeSeverity(extractString(payload, "level", "severity"))
+
+	fields := make(map[string]string, len(pairs))
+	for key, value := range pairs {
+		if isReservedKey(key) {
+			continue
+		}
+		fields[key] = value
+	}
+
+	if message == "" {
+		message = event.Line
+	}
+
+	return StructuredEvent{
+		SourceName: event.SourceName,
+		SourcePath: event.SourcePath,
+		Format:     "logfmt",
+		Timestamp:  timestamp,
+		ReceivedAt: event.ReceivedAt,
+		Severity:   severity,
+		Message:    message,
+		Fields:     fields,
+		Raw:        event.Line,
+	}, nil
+}
+
+// decodeLogfmt splits a line of key=value pairs. Values may be bare or
+// double-quoted with Go-style escapes; a key without "=" gets an empty value.
+func decodeLogfmt(line string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	i := 0
+	hasValue := false
+	for i < len(line) {
+		for i < len(line) && isLogfmtSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			break
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && !isLogfmtSpace(line[i]) && line[i] != '"' {
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("unexpected %q at offset %d", line[i], i)
+		}
+
+		if i >= len(line) || line[i] != '=' {
+			pairs[key] = ""
+			continue
+		}
+		i++
+		hasValue = true
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted value for %s", key)
+			}
+			quoted := line[i : end+1]
+			value, err := strconv.Unquote(quoted)
+			if err != nil {
+				value = quoted[1 : len(quoted)-1]
+			}
+			pairs[key] = value
+			i = end + 1
+			continue
+		}
+
+		start = i
+		for i < len(line) && !isLogfmtSpace(line[i]) {
+			i++
+		}
+		pairs[key] = line[start:i]
+	}
+
+	if !hasValue {
+		return nil, fmt.Errorf("no key=value pairs")
+	}
+	return pairs, nil
+}
+
+func isLogfmtSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+func isReservedKey(key string) bool {
+	switch key {
+	case "timestamp", "time", "ts", "msg", "message", "level", "severity":
+		return true
+	}
+	return false
+}
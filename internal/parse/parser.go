@@ -1,23 +1,182 @@
 package parse
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"go-log-aggregator/internal/config"
 	"go-log-aggregator/internal/ingest"
 )
 
+var builtinFormats = map[string]struct{}{
+	"json":   {},
+	"nginx":  {},
+	"apache": {},
+	"syslog": {},
+	"logfmt": {},
+	"grok":   {},
+}
+
+// Parser parses lines with the built-in formats plus the custom formats
+// and per-source grok patterns defined in config. A nil Parser knows only
+// the built-in formats.
+type Parser struct {
+	formats map[string]*regexFormat
+	grok    map[string]*regexFormat
+}
+
+func NewParser(cfg config.Config) (*Parser, error) {
+	var errs []error
+	engine := NewGrok()
+	for _, pattern := range cfg.GrokPatternFiles {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("grok patterns %s: %w", pattern, err))
+			continue
+		}
+		if len(paths) == 0 {
+			errs = append(errs, fmt.Errorf("grok patterns %s: no such file", pattern))
+			continue
+		}
+		for _, path := range paths {
+			if err := engine.LoadPatternFile(path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	parser := &Parser{
+		formats: make(map[string]*regexFormat, len(cfg.Formats)),
+		grok:    make(map[string]*regexFormat),
+	}
+	for _, format := range cfg.Formats {
+		name := strings.ToLower(strings.TrimSpace(format.Name))
+		if _, ok := builtinFormats[name]; ok {
+			errs = append(errs, fmt.Errorf("format %s: name is reserved for a built-in format", format.Name))
+			continue
+		}
+		if _, ok := parser.formats[name]; ok {
+			errs = append(errs, fmt.Errorf("format %s: defined more than once", format.Name))
+			continue
+		}
+		compiled, err := compileFormat(engine, format)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		parser.formats[name] = compiled
+	}
+
+	for _, src := range cfg.Sources {
+		if !strings.EqualFold(strings.TrimSpace(src.Format), "grok") {
+			continue
+		}
+		compiled, err := compileFormat(engine, config.Format{Name: "grok", Type: "grok", Pattern: src.Pattern})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", src.Name, err))
+			continue
+		}
+		parser.grok[src.Name] = compiled
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return parser, nil
+}
+
+// HasFormat reports whether format names a built-in or configured format.
+func (p *Parser) HasFormat(format string) bool {
+	name := strings.ToLower(strings.TrimSpace(format))
+	if _, ok := builtinFormats[name]; ok {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	_, ok := p.formats[name]
+	return ok
+}
+
 func ParseLine(format string, event ingest.Event) (StructuredEvent, error) {
-	switch strings.ToLower(strings.TrimSpace(format)) {
+	var parser *Parser
+	return parser.Parse(format, event)
+}
+
+func (p *Parser) Parse(format string, event ingest.Event) (StructuredEvent, error) {
+	parsed, err := p.parseEvent(strings.ToLower(strings.TrimSpace(format)), event)
+	if err != nil {
+		return StructuredEvent{}, err
+	}
+	if event.Peer != "" {
+		if parsed.Fields == nil {
+			parsed.Fields = make(map[string]string, 1)
+		}
+		parsed.Fields["peer"] = event.Peer
+	}
+	return parsed, nil
+}
+
+// Unparsed wraps a line that no format could parse.
+func Unparsed(event ingest.Event) StructuredEvent {
+	parsed := StructuredEvent{
+		SourceName: event.SourceName,
+		SourcePath: event.SourcePath,
+		Format:     "unknown",
+		ReceivedAt: event.ReceivedAt,
+		Severity:   "unknown",
+		Message:    event.Line,
+		Raw:        event.Line,
+	}
+	if event.Peer != "" {
+		parsed.Fields = map[string]string{"peer": event.Peer}
+	}
+	return parsed
+}
+
+func (p *Parser) parseEvent(normalized string, event ingest.Event) (StructuredEvent, error) {
+	if head, tail, ok := strings.Cut(event.Line, "\n"); ok && normalized != "json" {
+		// Multiline events are parsed by their first line; the remaining
+		// lines (e.g. a stack trace) extend the message and Raw keeps all.
+		first := event
+		first.Line = head
+		parsed, err := p.parseFormat(normalized, first)
+		if err != nil {
+			return StructuredEvent{}, err
+		}
+		parsed.Message += "\n" + tail
+		parsed.Raw = event.Line
+		return parsed, nil
+	}
+	return p.parseFormat(normalized, event)
+}
+
+func (p *Parser) parseFormat(format string, event ingest.Event) (StructuredEvent, error) {
+	switch format {
 	case "json":
 		return parseJSON(event)
 	case "nginx", "apache":
 		return parseNginx(event)
 	case "syslog":
 		return parseSyslog(event)
-	default:
-		return StructuredEvent{}, fmt.Errorf("unsupported format: %s", format)
+	case "logfmt":
+		return parseLogfmt(event)
+	case "grok":
+		if p != nil {
+			if compiled, ok := p.grok[event.SourceName]; ok {
+				return compiled.parse(event)
+			}
+		}
+		return StructuredEvent{}, fmt.Errorf("grok parse: no pattern configured for source %s", event.SourceName)
+	}
+
+	if p != nil {
+		if custom, ok := p.formats[format]; ok {
+			return custom.parse(event)
+		}
 	}
+	return StructuredEvent{}, fmt.Errorf("unsupported format: %s", format)
 }
 
 func normalizeSeverity(value string) string {
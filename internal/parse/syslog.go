@@ -3,6 +3,7 @@ package parse
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,8 +12,34 @@ import (
 
 var syslogRegex = regexp.MustCompile(`^(?P<month>[A-Z][a-z]{2})\s+(?P<day>\d{1,2})\s+(?P<time>\d{2}:\d{2}:\d{2})\s+(?P<host>\S+)\s+(?P<tag>[^:]+):\s*(?P<msg>.*)$`)
 
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+const syslogNil = "-"
+
 func parseSyslog(event ingest.Event) (StructuredEvent, error) {
-	matches := syslogRegex.FindStringSubmatch(event.Line)
+	line := event.Line
+	if !strings.HasPrefix(line, "<") {
+		return parseRFC3164(event, line, -1)
+	}
+
+	pri, rest, err := parsePRI(line)
+	if err != nil {
+		return StructuredEvent{}, fmt.Errorf("syslog parse: %w", err)
+	}
+	if version, body, ok := strings.Cut(rest, " "); ok && isDigits(version) {
+		return parseRFC5424(event, pri, version, body)
+	}
+	return parseRFC3164(event, rest, pri)
+}
+
+// parseRFC3164 handles BSD-style lines. Without a PRI header (pri < 0)
+// severity is guessed from the message text.
+func parseRFC3164(event ingest.Event, line string, pri int) (StructuredEvent, error) {
+	matches := syslogRegex.FindStringSubmatch(line)
 	if matches == nil {
 		return StructuredEvent{}, fmt.Errorf("syslog parse: no match")
 	}
@@ -38,6 +65,11 @@ func parseSyslog(event ingest.Event) (StructuredEvent, error) {
 		fields["pid"] = pid
 	}
 
+	if pri >= 0 {
+		severity = severityFromPRI(pri)
+		addPRIFields(fields, pri)
+	}
+
 	return StructuredEvent{
 		SourceName: event.SourceName,
 		SourcePath: event.SourcePath,
@@ -51,6 +83,161 @@ func parseSyslog(event ingest.Event) (StructuredEvent, error) {
 	}, nil
 }
 
+// parseRFC5424 handles
+// VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG].
+func parseRFC5424(event ingest.Event, pri int, version, body string) (StructuredEvent, error) {
+	parts := strings.SplitN(body, " ", 6)
+	if len(parts) < 6 {
+		return StructuredEvent{}, fmt.Errorf("syslog parse: truncated RFC 5424 header")
+	}
+	header, rest := parts[:5], parts[5]
+
+	sd, message, err := parseStructuredData(rest)
+	if err != nil {
+		return StructuredEvent{}, fmt.Errorf("syslog parse: %w", err)
+	}
+	message = strings.TrimPrefix(message, "\ufeff")
+
+	var timestamp time.Time
+	if header[0] != syslogNil {
+		timestamp, err = time.Parse(time.RFC3339Nano, header[0])
+		if err != nil {
+			return StructuredEvent{}, fmt.Errorf("syslog parse: invalid timestamp %q", header[0])
+		}
+	}
+
+	fields := map[string]string{"version": version}
+	for key, value := range sd {
+		fields[key] = value
+	}
+	for i, name := range []string{"host", "app_name", "procid", "msgid"} {
+		if value := header[i+1]; value != syslogNil {
+			fields[name] = value
+		}
+	}
+	addPRIFields(fields, pri)
+
+	return StructuredEvent{
+		SourceName: event.SourceName,
+		SourcePath: event.SourcePath,
+		Format:     "syslog",
+		Timestamp:  timestamp,
+		ReceivedAt: event.ReceivedAt,
+		Severity:   severityFromPRI(pri),
+		Message:    message,
+		Fields:     fields,
+		Raw:        event.Line,
+	}, nil
+}
+
+func parsePRI(line string) (int, string, error) {
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("invalid PRI header")
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", fmt.Errorf("invalid PRI value %q", line[1:end])
+	}
+	return pri, line[end+1:], nil
+}
+
+// parseStructuredData reads RFC 5424 SD-ELEMENTs into "id.param" keys and
+// returns the remaining message.
+func parseStructuredData(value string) (map[string]string, string, error) {
+	fields := make(map[string]string)
+	if value == syslogNil || strings.HasPrefix(value, syslogNil+" ") {
+		return fields, strings.TrimPrefix(strings.TrimPrefix(value, syslogNil), " "), nil
+	}
+	if value == "" {
+		return fields, "", nil
+	}
+
+	i := 0
+	for i < len(value) && value[i] == '[' {
+		i++
+		start := i
+		for i < len(value) && value[i] != ' ' && value[i] != ']' {
+			i++
+		}
+		id := value[start:i]
+		if id == "" {
+			return nil, "", fmt.Errorf("empty structured data id")
+		}
+
+		for i < len(value) && value[i] == ' ' {
+			i++
+			start = i
+			for i < len(value) && value[i] != '=' {
+				i++
+			}
+			if i+1 >= len(value) || value[i+1] != '"' {
+				return nil, "", fmt.Errorf("invalid structured data param in %s", id)
+			}
+			name := value[start:i]
+			i += 2
+
+			var param strings.Builder
+			for i < len(value) && value[i] != '"' {
+				if value[i] == '\\' && i+1 < len(value) && strings.IndexByte(`"\]`, value[i+1]) >= 0 {
+					i++
+				}
+				param.WriteByte(value[i])
+				i++
+			}
+			if i >= len(value) {
+				return nil, "", fmt.Errorf("unterminated structured data param in %s", id)
+			}
+			i++
+			fields[id+"."+name] = param.String()
+		}
+
+		if i >= len(value) || value[i] != ']' {
+			return nil, "", fmt.Errorf("unterminated structured data element %s", id)
+		}
+		i++
+	}
+
+	return fields, strings.TrimPrefix(value[i:], " "), nil
+}
+
+func addPRIFields(fields map[string]string, pri int) {
+	facility := pri / 8
+	if facility < len(syslogFacilities) {
+		fields["facility"] = syslogFacilities[facility]
+	} else {
+		fields["facility"] = strconv.Itoa(facility)
+	}
+	fields["pri"] = strconv.Itoa(pri)
+}
+
+func severityFromPRI(pri int) string {
+	switch pri % 8 {
+	case 0, 1, 2:
+		return "critical"
+	case 3:
+		return "error"
+	case 4:
+		return "warn"
+	case 5, 6:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 func parseSyslogTimestamp(month, day, clock string) time.Time {
 	year := time.Now().Year()
 	value := fmt.Sprintf("%s %s %s %d", month, day, clock, year)
@@ -0,0 +1,341 @@
+package silence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/filter"
+	"go-log-aggregator/internal/fsutil"
+)
+
+const (
+	StatePending = "pending"
+	StateActive  = "active"
+	StateExpired = "expired"
+)
+
+// expiredRetention is how long expired silences stay listed.
+const expiredRetention = 7 * 24 * time.Hour
+
+var (
+	ErrNotFound = errors.New("silence not found")
+	ErrInvalid  = errors.New("invalid silence")
+)
+
+// Matcher selects alerts by name: "rule" for the rule name, "group" for the
+// alert group, otherwise an event field such as source, severity or host.
+// Values match exactly, or as an anchored regular expression when Regex is
+// set.
+type Matcher struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Regex bool   `json:"regex,omitempty"`
+
+	re *regexp.Regexp
+}
+
+func (m *Matcher) compile() error {
+	m.Name = strings.TrimSpace(m.Name)
+	if m.Name == "" {
+		return fmt.Errorf("matcher name is required")
+	}
+	if !m.Regex {
+		return nil
+	}
+	re, err := regexp.Compile("^(?:" + m.Value + ")$")
+	if err != nil {
+		return fmt.Errorf("matcher %s: %w", m.Name, err)
+	}
+	m.re = re
+	return nil
+}
+
+func (m Matcher) matches(match alert.Match) bool {
+	var value string
+	switch strings.ToLower(m.Name) {
+	case "rule":
+		value = match.RuleName
+	case "group":
+		value = match.Group
+	default:
+		value, _ = filter.FieldValue(match.Event, m.Name)
+	}
+	if m.re != nil {
+		return m.re.MatchString(value)
+	}
+	return value == m.Value
+}
+
+// Silence mutes alerts matching all of its matchers between StartsAt and
+// EndsAt. Suppressed counts the alerts it muted.
+type Silence struct {
+	ID         string    `json:"id"`
+	Matchers   []Matcher `json:"matchers"`
+	StartsAt   time.Time `json:"startsAt"`
+	EndsAt     time.Time `json:"endsAt"`
+	CreatedBy  string    `json:"createdBy,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Suppressed int       `json:"suppressed"`
+	State      string    `json:"state,omitempty"`
+}
+
+func (s Silence) stateAt(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return StatePending
+	case now.Before(s.EndsAt):
+		return StateActive
+	default:
+		return StateExpired
+	}
+}
+
+func (s Silence) mutes(match alert.Match) bool {
+	for _, matcher := range s.Matchers {
+		if !matcher.matches(match) {
+			return false
+		}
+	}
+	return true
+}
+
+// Store keeps silences in memory and, when it has a path, on disk.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	silences []*Silence
+	dirty    bool
+}
+
+// Open loads the silences saved at path. An empty path keeps them in
+// memory only.
+func Open(path string) (*Store, error) {
+	store := &Store{path: path}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read silences: %w", err)
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store.silences); err != nil {
+		return nil, fmt.Errorf("parse silences: %w", err)
+	}
+	for _, silence := range store.silences {
+		for i := range silence.Matchers {
+			if err := silence.Matchers[i].compile(); err != nil {
+				return nil, fmt.Errorf("silence %s: %w", silence.ID, err)
+			}
+		}
+	}
+	return store, nil
+}
+
+// List returns all silences, newest first, with their state at now.
+func (s *Store) List(now time.Time) []Silence {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Silence, 0, len(s.silences))
+	for i := len(s.silences) - 1; i >= 0; i-- {
+		silence := *s.silences[i]
+		silence.State = silence.stateAt(now)
+		out = append(out, silence)
+	}
+	return out
+}
+
+// Add validates and saves a new silence. StartsAt defaults to now. If it
+// can't be saved it is not added either.
+func (s *Store) Add(silence Silence, now time.Time) (Silence, error) {
+	if s == nil {
+		return Silence{}, fmt.Errorf("silences disabled")
+	}
+	if len(silence.Matchers) == 0 {
+		return Silence{}, fmt.Errorf("%w: at least one matcher is required", ErrInvalid)
+	}
+	silence.Matchers = append([]Matcher(nil), silence.Matchers...)
+	for i := range silence.Matchers {
+		if err := silence.Matchers[i].compile(); err != nil {
+			return Silence{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if !silence.EndsAt.After(silence.StartsAt) || !silence.EndsAt.After(now) {
+		return Silence{}, fmt.Errorf("%w: endsAt must be after startsAt and in the future", ErrInvalid)
+	}
+	id, err := newID()
+	if err != nil {
+		return Silence{}, err
+	}
+	silence.ID = id
+	silence.CreatedAt = now
+	silence.Suppressed = 0
+	silence.State = ""
+
+	s.mu.Lock()
+	s.pruneLocked(now)
+	s.silences = append(s.silences, &silence)
+	s.dirty = true
+	s.mu.Unlock()
+
+	if err := s.Flush(); err != nil {
+		s.mu.Lock()
+		for i, added := range s.silences {
+			if added.ID == silence.ID {
+				s.silences = append(s.silences[:i], s.silences[i+1:]...)
+				break
+			}
+		}
+		s.mu.Unlock()
+		return Silence{}, err
+	}
+	saved := silence
+	saved.State = saved.stateAt(now)
+	return saved, nil
+}
+
+// Expire ends the silence with id at now. Expiring a pending silence
+// cancels it.
+func (s *Store) Expire(id string, now time.Time) (Silence, error) {
+	if s == nil {
+		return Silence{}, ErrNotFound
+	}
+	s.mu.Lock()
+	var found *Silence
+	for _, silence := range s.silences {
+		if silence.ID == id {
+			found = silence
+			break
+		}
+	}
+	if found == nil {
+		s.mu.Unlock()
+		return Silence{}, ErrNotFound
+	}
+	if found.stateAt(now) != StateExpired {
+		if found.StartsAt.After(now) {
+			found.StartsAt = now
+		}
+		found.EndsAt = now
+		s.dirty = true
+	}
+	expired := *found
+	expired.State = StateExpired
+	s.mu.Unlock()
+	return expired, s.Flush()
+}
+
+// Muted reports whether an active silence covers match, and counts the
+// suppressed alert against it.
+func (s *Store) Muted(match alert.Match, now time.Time) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, silence := range s.silences {
+		if silence.stateAt(now) == StateActive && silence.mutes(match) {
+			silence.Suppressed++
+			s.dirty = true
+			return silence.ID, true
+		}
+	}
+	return "", false
+}
+
+func (s *Store) pruneLocked(now time.Time) {
+	kept := s.silences[:0]
+	for _, silence := range s.silences {
+		if now.Sub(silence.EndsAt) > expiredRetention {
+			s.dirty = true
+			continue
+		}
+		kept = append(kept, silence)
+	}
+	for i := len(kept); i < len(s.silences); i++ {
+		s.silences[i] = nil
+	}
+	s.silences = kept
+}
+
+// Flush writes the silences to disk if anything changed.
+func (s *Store) Flush() error {
+	if s == nil || s.path == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	s.pruneLocked(time.Now())
+	data, err := json.MarshalIndent(s.silences, "", "  ")
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal silences: %w", err)
+	}
+
+	if err := fsutil.WriteFileAtomic(s.path, data); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return fmt.Errorf("write silences: %w", err)
+	}
+	return nil
+}
+
+// Run flushes suppression counts every interval until ctx is done.
+func (s *Store) Run(ctx context.Context, interval time.Duration, errs chan<- error) {
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil && errs != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("silence id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
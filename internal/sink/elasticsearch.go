@@ -0,0 +1,101 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+)
+
+const defaultIndex = "logs-{date}"
+
+// Elasticsearch indexes events through the _bulk API (also served by
+// OpenSearch). "{date}" in the index name becomes the event's UTC date,
+// e.g. logs-2024.05.01.
+type Elasticsearch struct {
+	client httpClient
+	url    string
+	index  string
+}
+
+func newElasticsearch(cfg config.Sink, timeout time.Duration) (*Elasticsearch, error) {
+	if strings.TrimSpace(cfg.URL) == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	index := strings.TrimSpace(cfg.Index)
+	if index == "" {
+		index = defaultIndex
+	}
+	return &Elasticsearch{
+		client: newHTTPClient(timeout, cfg.Headers),
+		url:    strings.TrimRight(cfg.URL, "/") + "/_bulk",
+		index:  index,
+	}, nil
+}
+
+func (e *Elasticsearch) Write(ctx context.Context, events []parse.StructuredEvent) error {
+	var body bytes.Buffer
+	for _, event := range events {
+		doc, err := (output.JSON{}).Format(event)
+		if err != nil {
+			continue
+		}
+		index := strings.ReplaceAll(e.index, "{date}", eventTime(event).UTC().Format("2006.01.02"))
+		action, _ := json.Marshal(map[string]map[string]string{"index": {"_index": index}})
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+
+	data, err := e.client.post(ctx, e.url, "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The request as a whole succeeded; individual documents may still
+	// have been rejected (mapping conflicts and the like). Those are
+	// logged rather than retried, since resending would duplicate the
+	// accepted ones.
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return Permanent(fmt.Errorf("decode bulk response: %w", err))
+	}
+	if !result.Errors {
+		return nil
+	}
+	failed := 0
+	var first string
+	for _, item := range result.Items {
+		for _, status := range item {
+			if status.Status >= 300 {
+				failed++
+				if first == "" {
+					first = string(status.Error)
+				}
+			}
+		}
+	}
+	log.Printf("sink elasticsearch: %d of %d documents rejected: %s", failed, len(events), first)
+	return nil
+}
+
+func (e *Elasticsearch) Close() error {
+	return nil
+}
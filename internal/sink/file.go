@@ -0,0 +1,223 @@
+package sink
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+)
+
+const rotateTimeFormat = "20060102T150405.000"
+
+// File appends events as lines (JSON by default) to a local file and rotates it by
+// size and age. Rotated files are named <path>.<time>, optionally gzipped,
+// and only the newest MaxFiles are kept.
+type File struct {
+	path     string
+	maxSize  int64
+	maxAge   time.Duration
+	maxFiles int
+	compress bool
+	format   output.Formatter
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newFile(cfg config.Sink) (*File, error) {
+	if strings.TrimSpace(cfg.Path) == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	if cfg.MaxSize < 0 || cfg.MaxFiles < 0 {
+		return nil, fmt.Errorf("maxSize and maxFiles must not be negative")
+	}
+	format, err := lineFormatter(cfg, output.JSON{})
+	if err != nil {
+		return nil, err
+	}
+	var maxAge time.Duration
+	if cfg.MaxAge != "" {
+		if maxAge, err = parseDuration("maxAge", cfg.MaxAge, 0); err != nil {
+			return nil, err
+		}
+	}
+	return &File{
+		path:     cfg.Path,
+		maxSize:  cfg.MaxSize,
+		maxAge:   maxAge,
+		maxFiles: cfg.MaxFiles,
+		compress: cfg.Compress,
+		format:   format,
+	}, nil
+}
+
+// Write appends the batch. Once some of its lines are in the file a
+// failure is Permanent, since retrying the batch would duplicate them.
+func (f *File) Write(ctx context.Context, events []parse.StructuredEvent) error {
+	written := 0
+	fail := func(err error) error {
+		if written > 0 {
+			return Permanent(fmt.Errorf("%w (after %d of %d events)", err, written, len(events)))
+		}
+		return err
+	}
+	for _, event := range events {
+		line, err := f.format.Format(event)
+		if err != nil {
+			continue
+		}
+		line = append(line, '\n')
+
+		now := time.Now()
+		if f.file != nil && f.size > 0 && f.due(now, int64(len(line))) {
+			if err := f.rotate(now); err != nil {
+				return fail(err)
+			}
+		}
+		if f.file == nil {
+			if err := f.open(now); err != nil {
+				return fail(err)
+			}
+		}
+		n, err := f.file.Write(line)
+		f.size += int64(n)
+		if n > 0 {
+			written++
+		}
+		if err != nil {
+			// Start over on a fresh handle for the next batch.
+			_ = f.file.Close()
+			f.file = nil
+			return fail(fmt.Errorf("write %s: %w", f.path, err))
+		}
+	}
+	return nil
+}
+
+func (f *File) due(now time.Time, next int64) bool {
+	if f.maxSize > 0 && f.size+next > f.maxSize {
+		return true
+	}
+	return f.maxAge > 0 && now.Sub(f.openedAt) >= f.maxAge
+}
+
+func (f *File) open(now time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return fmt.Errorf("create sink dir: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat %s: %w", f.path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = now
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (f *File) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", f.path, err)
+	}
+	f.file = nil
+
+	rotated := f.path + "." + now.UTC().Format(rotateTimeFormat)
+	if err := os.Rename(f.path, rotated); err != nil {
+		return fmt.Errorf("rotate %s: %w", f.path, err)
+	}
+	if f.compress {
+		if err := gzipFile(rotated); err != nil {
+			return err
+		}
+	}
+	return f.prune()
+}
+
+// prune removes the oldest rotated files beyond maxFiles.
+func (f *File) prune() error {
+	if f.maxFiles <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(f.path + ".*")
+	if err != nil {
+		return err
+	}
+	var rotated []string
+	for _, match := range matches {
+		if !strings.HasSuffix(match, ".tmp") {
+			rotated = append(rotated, match)
+		}
+	}
+	// Timestamps sort lexically.
+	sort.Strings(rotated)
+	for len(rotated) > f.maxFiles {
+		if err := os.Remove(rotated[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", rotated[0], err)
+		}
+		rotated = rotated[1:]
+	}
+	return nil
+}
+
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("compress %s: %w", path, err)
+	}
+
+	tmp := path + ".gz.tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		_ = in.Close()
+		return fmt.Errorf("compress %s: %w", path, err)
+	}
+	buf := bufio.NewWriter(out)
+	gz := gzip.NewWriter(buf)
+	_, err = io.Copy(gz, in)
+	_ = in.Close()
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("compress %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpClient posts request bodies. Network errors, 429s and 5xx responses
+// are returned for retry; other failures are permanent.
+type httpClient struct {
+	client  *http.Client
+	headers map[string]string
+}
+
+func newHTTPClient(timeout time.Duration, headers map[string]string) httpClient {
+	return httpClient{client: &http.Client{Timeout: timeout}, headers: headers}
+}
+
+func (c httpClient) post(ctx context.Context, url, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return data, nil
+	}
+	err = fmt.Errorf("post %s: %s: %s", url, resp.Status, bytes.TrimSpace(truncate(data, 512)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, err
+	}
+	return nil, Permanent(err)
+}
+
+func truncate(data []byte, n int) []byte {
+	if len(data) > n {
+		return data[:n]
+	}
+	return data
+}
@@ -0,0 +1,135 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+)
+
+const lokiPushPath = "/loki/api/v1/push"
+
+// Loki pushes events to the Loki push API. Each stream is labelled with
+// the configured labels plus the event's source and severity; the line is
+// the event's JSON unless another format is set.
+type Loki struct {
+	client httpClient
+	url    string
+	labels map[string]string
+	format output.Formatter
+}
+
+func newLoki(cfg config.Sink, timeout time.Duration) (*Loki, error) {
+	if strings.TrimSpace(cfg.URL) == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	parsed, err := url.Parse(strings.TrimSpace(cfg.URL))
+	if err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+	if parsed.Path == "" || parsed.Path == "/" {
+		parsed.Path = lokiPushPath
+	}
+	format, err := lineFormatter(cfg, output.JSON{})
+	if err != nil {
+		return nil, err
+	}
+	return &Loki{
+		client: newHTTPClient(timeout, cfg.Headers),
+		url:    parsed.String(),
+		labels: cfg.Labels,
+		format: format,
+	}, nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+	sortBy []int64
+}
+
+func (l *Loki) Write(ctx context.Context, events []parse.StructuredEvent) error {
+	streams := make(map[string]*lokiStream)
+	var keys []string
+	for _, event := range events {
+		line, err := l.format.Format(event)
+		if err != nil {
+			continue
+		}
+		labels := make(map[string]string, len(l.labels)+2)
+		for key, value := range l.labels {
+			labels[key] = value
+		}
+		labels["source"] = event.SourceName
+		if event.Severity != "" {
+			labels["severity"] = event.Severity
+		}
+
+		key := labelKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		ts := eventTime(event).UnixNano()
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(ts, 10), string(line)})
+		stream.sortBy = append(stream.sortBy, ts)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		stream := streams[key]
+		sort.Stable(byTime(*stream))
+		payload.Streams = append(payload.Streams, stream)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+	_, err = l.client.post(ctx, l.url, "application/json", body)
+	return err
+}
+
+// byTime orders a stream's values oldest first, as Loki expects.
+type byTime lokiStream
+
+func (s byTime) Len() int           { return len(s.Values) }
+func (s byTime) Less(i, j int) bool { return s.sortBy[i] < s.sortBy[j] }
+func (s byTime) Swap(i, j int) {
+	s.Values[i], s.Values[j] = s.Values[j], s.Values[i]
+	s.sortBy[i], s.sortBy[j] = s.sortBy[j], s.sortBy[i]
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(labels[key])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func (l *Loki) Close() error {
+	return nil
+}
@@ -0,0 +1,327 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+)
+
+const (
+	TypeFile          = "file"
+	TypeElasticsearch = "elasticsearch"
+	TypeLoki          = "loki"
+	TypeSyslog        = "syslog"
+)
+
+const (
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 10000
+	defaultRetries       = 5
+	defaultBackoff       = time.Second
+	defaultTimeout       = 30 * time.Second
+	maxBackoff           = 30 * time.Second
+	// drainTimeout bounds how long Close waits for queued events.
+	drainTimeout = 10 * time.Second
+)
+
+// Sink writes batches of events. Write is only called from one goroutine
+// at a time. Errors wrapped with Permanent are not retried.
+type Sink interface {
+	Write(ctx context.Context, events []parse.StructuredEvent) error
+	Close() error
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, e.g. a rejected request.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// New builds the sink described by cfg.
+func New(cfg config.Sink) (Sink, error) {
+	timeout, err := parseDuration("timeout", cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Type)) {
+	case TypeFile:
+		return newFile(cfg)
+	case TypeElasticsearch:
+		return newElasticsearch(cfg, timeout)
+	case TypeLoki:
+		return newLoki(cfg, timeout)
+	case TypeSyslog:
+		return newSyslog(cfg, timeout)
+	default:
+		return nil, fmt.Errorf("type must be file, elasticsearch, loki or syslog")
+	}
+}
+
+// lineFormatter builds the formatter configured for a sink. fallback is
+// used when no format is set; it may be nil.
+func lineFormatter(cfg config.Sink, fallback output.Formatter) (output.Formatter, error) {
+	if strings.TrimSpace(cfg.Format) == "" {
+		return fallback, nil
+	}
+	if strings.EqualFold(strings.TrimSpace(cfg.Format), output.ModeNone) {
+		return nil, fmt.Errorf("format none is not supported for sinks")
+	}
+	return output.New(cfg.Format, output.Options{Template: cfg.Template})
+}
+
+func eventTime(event parse.StructuredEvent) time.Time {
+	if !event.Timestamp.IsZero() {
+		return event.Timestamp
+	}
+	if !event.ReceivedAt.IsZero() {
+		return event.ReceivedAt
+	}
+	return time.Now()
+}
+
+func parseDuration(name, value string, fallback time.Duration) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return fallback, nil
+	}
+	dur, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || dur <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration", name)
+	}
+	return dur, nil
+}
+
+// runner feeds one sink from a bounded queue, batching and retrying.
+type runner struct {
+	name          string
+	sink          Sink
+	queue         chan parse.StructuredEvent
+	batchSize     int
+	flushInterval time.Duration
+	retries       int
+	backoff       time.Duration
+	dropped       atomic.Int64
+	done          chan struct{}
+}
+
+func newRunner(cfg config.Sink, sink Sink) (*runner, error) {
+	flushInterval, err := parseDuration("flushInterval", cfg.FlushInterval, defaultFlushInterval)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := parseDuration("backoff", cfg.Backoff, defaultBackoff)
+	if err != nil {
+		return nil, err
+	}
+	r := &runner{
+		name:          cfg.Name,
+		sink:          sink,
+		batchSize:     cfg.BatchSize,
+		flushInterval: flushInterval,
+		retries:       defaultRetries,
+		backoff:       backoff,
+		done:          make(chan struct{}),
+	}
+	if r.name == "" {
+		r.name = strings.ToLower(strings.TrimSpace(cfg.Type))
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultBatchSize
+	}
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	r.queue = make(chan parse.StructuredEvent, bufferSize)
+	if cfg.Retries != nil && *cfg.Retries >= 0 {
+		r.retries = *cfg.Retries
+	}
+	return r, nil
+}
+
+func (r *runner) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]parse.StructuredEvent, 0, r.batchSize)
+	flush := func() {
+		if dropped := r.dropped.Swap(0); dropped > 0 {
+			log.Printf("sink %s: buffer full, dropped %d events", r.name, dropped)
+		}
+		if len(batch) == 0 {
+			return
+		}
+		r.write(ctx, batch)
+		batch = make([]parse.StructuredEvent, 0, r.batchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-r.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= r.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (r *runner) write(ctx context.Context, batch []parse.StructuredEvent) {
+	backoff := r.backoff
+	for attempt := 0; ; attempt++ {
+		err := r.sink.Write(ctx, batch)
+		if err == nil {
+			return
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) || attempt >= r.retries || ctx.Err() != nil {
+			log.Printf("sink %s: dropping %d events: %v", r.name, len(batch), err)
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("sink %s: dropping %d events: %v", r.name, len(batch), err)
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Manager fans events out to the configured sinks. Each sink has its own
+// queue and goroutine, so a slow or failing sink only drops its own
+// events.
+type Manager struct {
+	mu      sync.RWMutex
+	closed  bool
+	runners []*runner
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+func NewManager(cfgs []config.Sink) (*Manager, error) {
+	m := &Manager{}
+	var errs []error
+	for i, cfg := range cfgs {
+		sink, err := New(cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink[%d]: %w", i, err))
+			continue
+		}
+		r, err := newRunner(cfg, sink)
+		if err != nil {
+			_ = sink.Close()
+			errs = append(errs, fmt.Errorf("sink[%d]: %w", i, err))
+			continue
+		}
+		m.runners = append(m.runners, r)
+	}
+	if len(errs) > 0 {
+		m.closeSinks()
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+// Start launches delivery. Sinks keep running until Close.
+func (m *Manager) Start() {
+	if m == nil {
+		return
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	for _, r := range m.runners {
+		go r.run(m.ctx)
+	}
+}
+
+// Send queues event for every sink without blocking.
+func (m *Manager) Send(event parse.StructuredEvent) {
+	if m == nil {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return
+	}
+	for _, r := range m.runners {
+		select {
+		case r.queue <- event:
+		default:
+			r.dropped.Add(1)
+		}
+	}
+}
+
+// Close flushes queued events, waiting up to drainTimeout, and closes
+// the sinks.
+func (m *Manager) Close() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	for _, r := range m.runners {
+		close(r.queue)
+	}
+	m.mu.Unlock()
+
+	if m.cancel != nil {
+		timer := time.NewTimer(drainTimeout)
+		defer timer.Stop()
+		for _, r := range m.runners {
+			select {
+			case <-r.done:
+			case <-timer.C:
+				m.cancel()
+				<-r.done
+			}
+		}
+		m.cancel()
+	}
+	return m.closeSinks()
+}
+
+func (m *Manager) closeSinks() error {
+	var errs []error
+	for _, r := range m.runners {
+		if err := r.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", r.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,153 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+)
+
+// facilityLocal0 is the facility forwarded messages are sent with.
+const facilityLocal0 = 16
+
+// Syslog forwards events as RFC 5424 messages over UDP, or over TCP with
+// octet-counting framing (RFC 6587). The app name is the event's source;
+// the message is the event's message unless a format is set.
+type Syslog struct {
+	network  string
+	address  string
+	tag      string
+	hostname string
+	timeout  time.Duration
+	format   output.Formatter
+	conn     net.Conn
+}
+
+func newSyslog(cfg config.Sink, timeout time.Duration) (*Syslog, error) {
+	if strings.TrimSpace(cfg.Address) == "" {
+		return nil, fmt.Errorf("address is required")
+	}
+	network := strings.ToLower(strings.TrimSpace(cfg.Protocol))
+	switch network {
+	case "":
+		network = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("protocol must be udp or tcp")
+	}
+	tag := strings.TrimSpace(cfg.Tag)
+	if tag == "" {
+		tag = "go-log-aggregator"
+	}
+	format, err := lineFormatter(cfg, nil)
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &Syslog{
+		network:  network,
+		address:  cfg.Address,
+		tag:      tag,
+		hostname: hostname,
+		timeout:  timeout,
+		format:   format,
+	}, nil
+}
+
+func (s *Syslog) Write(ctx context.Context, events []parse.StructuredEvent) error {
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.timeout}
+		conn, err := dialer.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return fmt.Errorf("dial %s: %w", s.address, err)
+		}
+		s.conn = conn
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	for _, event := range events {
+		msg := s.message(event)
+		if s.network == "tcp" {
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// Reconnect when the batch is retried.
+			_ = s.conn.Close()
+			s.conn = nil
+			return fmt.Errorf("write %s: %w", s.address, err)
+		}
+	}
+	return nil
+}
+
+func (s *Syslog) message(event parse.StructuredEvent) string {
+	appName := syslogName(event.SourceName, 48)
+	if appName == "-" {
+		appName = syslogName(s.tag, 48)
+	}
+	message := event.Message
+	if message == "" {
+		message = event.Raw
+	}
+	if s.format != nil {
+		if line, err := s.format.Format(event); err == nil {
+			message = string(line)
+		}
+	}
+	pri := facilityLocal0*8 + syslogSeverity(event.Severity)
+	return fmt.Sprintf("<%d>1 %s %s %s - - - %s",
+		pri, eventTime(event).UTC().Format(time.RFC3339Nano), syslogName(s.hostname, 255), appName, message)
+}
+
+// syslogName makes value a valid RFC 5424 header field: printable ASCII
+// without spaces, at most max bytes, or "-" when empty.
+func syslogName(value string, max int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r > ' ' && r < 127 {
+			b.WriteRune(r)
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+func syslogSeverity(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical", "fatal", "panic":
+		return 2
+	case "error":
+		return 3
+	case "warn", "warning":
+		return 4
+	case "info":
+		return 6
+	case "debug", "trace":
+		return 7
+	default:
+		return 5
+	}
+}
+
+func (s *Syslog) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,411 @@
+package web
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/filter"
+)
+
+const (
+	defaultAggregateRange = time.Hour
+	targetBuckets         = 60
+	maxBuckets            = 2000
+	maxGroups             = 10
+	defaultTopN           = 10
+	otherGroup            = "other"
+	missingGroup          = "(none)"
+	// maxTopValues bounds the distinct values counted per top field.
+	maxTopValues = 1000
+	// statsSampleSize bounds the values kept per stats field for percentiles.
+	statsSampleSize = 10000
+)
+
+var bucketIntervals = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// AggregateRequest describes a histogram over stored events plus optional
+// top-N values and numeric statistics for named fields.
+type AggregateRequest struct {
+	Query    filter.Node
+	Sources  []string
+	From     time.Time
+	To       time.Time
+	Interval time.Duration
+	// GroupBy splits each bucket by severity, source, format or any field.
+	GroupBy string
+	Top     []string
+	TopN    int
+	Stats   []string
+}
+
+type AggregateResult struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval"`
+	Total    int       `json:"total"`
+	// Groups lists the group names by descending count; the least common
+	// are folded into "other".
+	Groups  []string                `json:"groups,omitempty"`
+	Buckets []Bucket                `json:"buckets"`
+	Top     map[string][]ValueCount `json:"top,omitempty"`
+	Stats   map[string]FieldStats   `json:"stats,omitempty"`
+}
+
+type Bucket struct {
+	Start  time.Time      `json:"start"`
+	Count  int            `json:"count"`
+	Groups map[string]int `json:"groups,omitempty"`
+}
+
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// FieldStats summarizes the numeric values of a field; events where it is
+// missing or not a number are skipped. Percentiles are exact up to
+// statsSampleSize values and estimated from a uniform sample beyond that.
+type FieldStats struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Sum   float64 `json:"sum"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+}
+
+func Aggregate(store EventStore, req AggregateRequest, now time.Time) (AggregateResult, error) {
+	to := req.To
+	if to.IsZero() {
+		to = now
+	}
+	from := req.From
+	if from.IsZero() {
+		from = to.Add(-defaultAggregateRange)
+	}
+	if !from.Before(to) {
+		return AggregateResult{}, fmt.Errorf("from must be before to")
+	}
+
+	interval := req.Interval
+	if interval <= 0 {
+		interval = autoInterval(to.Sub(from))
+	}
+	start := from.Truncate(interval)
+	count := int(to.Sub(start)/interval) + 1
+	if count > maxBuckets {
+		return AggregateResult{}, fmt.Errorf("interval %s is too small for the range (%d buckets, max %d)", interval, count, maxBuckets)
+	}
+	topN := req.TopN
+	if topN <= 0 {
+		topN = defaultTopN
+	}
+
+	result := AggregateResult{
+		From:     from,
+		To:       to,
+		Interval: interval.String(),
+		Buckets:  make([]Bucket, count),
+	}
+	for i := range result.Buckets {
+		result.Buckets[i].Start = start.Add(time.Duration(i) * interval)
+	}
+
+	groupTotals := make(map[string]int)
+	bucketGroups := make([]map[string]int, count)
+	topCounts := make(map[string]*topCounter, len(req.Top))
+	for _, field := range req.Top {
+		topCounts[field] = newTopCounter(maxTopValues)
+	}
+	summaries := make(map[string]*fieldSummary, len(req.Stats))
+	for _, field := range req.Stats {
+		summaries[field] = &fieldSummary{}
+	}
+
+	if store != nil {
+		store.Scan(req.Sources, from, func(event Event) bool {
+			if event.Timestamp.After(to) {
+				return true
+			}
+			structured := event.Structured()
+			if req.Query != nil && !req.Query.Matches(structured) {
+				return true
+			}
+			index := int(event.Timestamp.Sub(start) / interval)
+			if index < 0 || index >= count {
+				return true
+			}
+			result.Total++
+			result.Buckets[index].Count++
+
+			if req.GroupBy != "" {
+				group, ok := filter.FieldValue(structured, req.GroupBy)
+				if !ok || group == "" {
+					group = missingGroup
+				}
+				groupTotals[group]++
+				if bucketGroups[index] == nil {
+					bucketGroups[index] = make(map[string]int)
+				}
+				bucketGroups[index][group]++
+			}
+			for field, counts := range topCounts {
+				if value, ok := filter.FieldValue(structured, field); ok && value != "" {
+					counts.add(value)
+				}
+			}
+			for field, summary := range summaries {
+				value, ok := filter.FieldValue(structured, field)
+				if !ok {
+					continue
+				}
+				number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+					continue
+				}
+				summary.add(number)
+			}
+			return true
+		})
+	}
+
+	if req.GroupBy != "" {
+		ranked := rankCounts(groupTotals, 0)
+		kept := make(map[string]struct{}, maxGroups)
+		for i, item := range ranked {
+			if i < maxGroups {
+				kept[item.Value] = struct{}{}
+				result.Groups = append(result.Groups, item.Value)
+			}
+		}
+		if len(ranked) > maxGroups {
+			result.Groups = append(result.Groups, otherGroup)
+		}
+		for i, groups := range bucketGroups {
+			if groups == nil {
+				continue
+			}
+			folded := make(map[string]int, len(groups))
+			for group, n := range groups {
+				if _, ok := kept[group]; !ok {
+					group = otherGroup
+				}
+				folded[group] += n
+			}
+			result.Buckets[i].Groups = folded
+		}
+	}
+
+	if len(topCounts) > 0 {
+		result.Top = make(map[string][]ValueCount, len(topCounts))
+		for field, counts := range topCounts {
+			result.Top[field] = counts.ranked(topN)
+		}
+	}
+
+	if len(summaries) > 0 {
+		result.Stats = make(map[string]FieldStats, len(summaries))
+		for field, summary := range summaries {
+			result.Stats[field] = summary.result()
+		}
+	}
+	return result, nil
+}
+
+func autoInterval(span time.Duration) time.Duration {
+	for _, interval := range bucketIntervals {
+		if span/interval <= targetBuckets {
+			return interval
+		}
+	}
+	return bucketIntervals[len(bucketIntervals)-1]
+}
+
+// rankCounts orders counts descending (ties by value) and keeps the first
+// limit entries; limit <= 0 keeps all.
+func rankCounts(counts map[string]int, limit int) []ValueCount {
+	out := make([]ValueCount, 0, len(counts))
+	for value, n := range counts {
+		out = append(out, ValueCount{Value: value, Count: n})
+	}
+	return sortCounts(out, limit)
+}
+
+func sortCounts(out []ValueCount, limit int) []ValueCount {
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// topCounter counts values with the space-saving algorithm: once limit
+// values are tracked, a new value replaces the least counted one and
+// inherits its count. Frequent values are kept, and a count is over by at
+// most the count it inherited.
+type topCounter struct {
+	limit  int
+	counts []ValueCount
+	index  map[string]int
+}
+
+func newTopCounter(limit int) *topCounter {
+	return &topCounter{limit: limit, index: make(map[string]int)}
+}
+
+func (c *topCounter) add(value string) {
+	if i, ok := c.index[value]; ok {
+		c.counts[i].Count++
+		heap.Fix(c, i)
+		return
+	}
+	if len(c.counts) < c.limit {
+		heap.Push(c, ValueCount{Value: value, Count: 1})
+		return
+	}
+	delete(c.index, c.counts[0].Value)
+	c.counts[0].Value = value
+	c.counts[0].Count++
+	c.index[value] = 0
+	heap.Fix(c, 0)
+}
+
+func (c *topCounter) ranked(limit int) []ValueCount {
+	return sortCounts(slices.Clone(c.counts), limit)
+}
+
+func (c *topCounter) Len() int           { return len(c.counts) }
+func (c *topCounter) Less(i, j int) bool { return c.counts[i].Count < c.counts[j].Count }
+func (c *topCounter) Swap(i, j int) {
+	c.counts[i], c.counts[j] = c.counts[j], c.counts[i]
+	c.index[c.counts[i].Value] = i
+	c.index[c.counts[j].Value] = j
+}
+func (c *topCounter) Push(x any) {
+	item := x.(ValueCount)
+	c.index[item.Value] = len(c.counts)
+	c.counts = append(c.counts, item)
+}
+func (c *topCounter) Pop() any {
+	last := c.counts[len(c.counts)-1]
+	c.counts = c.counts[:len(c.counts)-1]
+	delete(c.index, last.Value)
+	return last
+}
+
+// fieldSummary keeps running totals for a stats field plus a reservoir
+// sample of its values for the percentiles.
+type fieldSummary struct {
+	stats  FieldStats
+	sample []float64
+}
+
+func (s *fieldSummary) add(value float64) {
+	if s.stats.Count == 0 || value < s.stats.Min {
+		s.stats.Min = value
+	}
+	if s.stats.Count == 0 || value > s.stats.Max {
+		s.stats.Max = value
+	}
+	s.stats.Count++
+	s.stats.Sum += value
+
+	if len(s.sample) < statsSampleSize {
+		s.sample = append(s.sample, value)
+	} else if i := rand.IntN(s.stats.Count); i < statsSampleSize {
+		s.sample[i] = value
+	}
+}
+
+func (s *fieldSummary) result() FieldStats {
+	stats := s.stats
+	if stats.Count == 0 {
+		return stats
+	}
+	stats.Avg = stats.Sum / float64(stats.Count)
+	sort.Float64s(s.sample)
+	stats.P50 = percentile(s.sample, 0.50)
+	stats.P90 = percentile(s.sample, 0.90)
+	stats.P95 = percentile(s.sample, 0.95)
+	stats.P99 = percentile(s.sample, 0.99)
+	return stats
+}
+
+// percentile interpolates linearly between the closest ranks of sorted.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func handleAggregate(w http.ResponseWriter, r *http.Request, store EventStore) {
+	now := time.Now()
+	req, err := parseAggregateRequest(r, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := Aggregate(store, req, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func parseAggregateRequest(r *http.Request, now time.Time) (AggregateRequest, error) {
+	values := r.URL.Query()
+	var req AggregateRequest
+
+	var err error
+	if req.Query, req.Sources, req.From, req.To, err = parseSelection(r, now); err != nil {
+		return AggregateRequest{}, err
+	}
+
+	if value := strings.TrimSpace(values.Get("interval")); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return AggregateRequest{}, fmt.Errorf("interval must be a positive duration")
+		}
+		req.Interval = interval
+	}
+	req.GroupBy = strings.TrimSpace(values.Get("groupBy"))
+	req.Top = parseList(values.Get("top"))
+	req.Stats = parseList(values.Get("stats"))
+
+	if value := strings.TrimSpace(values.Get("topN")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return AggregateRequest{}, fmt.Errorf("topN must be a positive number")
+		}
+		req.TopN = n
+	}
+	return req, nil
+}
@@ -0,0 +1,201 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+)
+
+const defaultAlertLimit = 100
+
+// AlertRecord is one alert as it was raised: a per-line match, or a
+// windowed rule firing or resolving. Events holds the events that
+// triggered it.
+type AlertRecord struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Rule       string    `json:"rule"`
+	State      string    `json:"state,omitempty"`
+	Group      string    `json:"group,omitempty"`
+	Count      int       `json:"count,omitempty"`
+	Value      float64   `json:"value,omitempty"`
+	Threshold  float64   `json:"threshold,omitempty"`
+	Window     string    `json:"window,omitempty"`
+	Suppressed int       `json:"suppressed,omitempty"`
+	SilencedBy string    `json:"silencedBy,omitempty"`
+	Events     []Event   `json:"events,omitempty"`
+}
+
+type AlertRuleStats struct {
+	Rule      string    `json:"rule"`
+	Alerts    int       `json:"alerts"`
+	Fired     int       `json:"fired"`
+	Resolved  int       `json:"resolved"`
+	Silenced  int       `json:"silenced"`
+	Firing    int       `json:"firing"`
+	LastAlert time.Time `json:"lastAlert"`
+}
+
+type AlertsSnapshot struct {
+	Firing  []AlertRecord    `json:"firing"`
+	History []AlertRecord    `json:"history"`
+	Rules   []AlertRuleStats `json:"rules"`
+}
+
+// AlertHistory keeps the most recent alerts, which windowed alerts are
+// firing, and counts per rule. It is safe for concurrent use.
+type AlertHistory struct {
+	mu      sync.Mutex
+	records []AlertRecord
+	next    int
+	full    bool
+	lastID  int64
+	firing  map[string]AlertRecord
+	stats   map[string]*AlertRuleStats
+}
+
+func NewAlertHistory(size int) *AlertHistory {
+	if size <= 0 {
+		size = 1000
+	}
+	return &AlertHistory{
+		records: make([]AlertRecord, size),
+		firing:  make(map[string]AlertRecord),
+		stats:   make(map[string]*AlertRuleStats),
+	}
+}
+
+// Record adds match to the history. silencedBy names the silence that
+// muted it, if any.
+func (h *AlertHistory) Record(match alert.Match, silencedBy string, at time.Time) AlertRecord {
+	record := AlertRecord{
+		Time:       at,
+		Rule:       match.RuleName,
+		State:      match.State,
+		Group:      match.Group,
+		Count:      match.Count,
+		Value:      match.Value,
+		Threshold:  match.Threshold,
+		Suppressed: match.Suppressed,
+		SilencedBy: silencedBy,
+	}
+	if match.Window > 0 {
+		record.Window = match.Window.String()
+	}
+	samples := match.Samples
+	if len(samples) == 0 && (match.Event.Raw != "" || match.Event.Message != "") {
+		samples = append(samples, match.Event)
+	}
+	for _, sample := range samples {
+		record.Events = append(record.Events, NewEvent(sample))
+	}
+	if h == nil {
+		return record
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastID++
+	record.ID = h.lastID
+
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+
+	key := record.Rule + "\x00" + record.Group
+	switch record.State {
+	case alert.StateFiring:
+		h.firing[key] = record
+	case alert.StateResolved:
+		delete(h.firing, key)
+	}
+
+	stats, ok := h.stats[record.Rule]
+	if !ok {
+		stats = &AlertRuleStats{Rule: record.Rule}
+		h.stats[record.Rule] = stats
+	}
+	stats.Alerts++
+	stats.LastAlert = at
+	switch record.State {
+	case alert.StateFiring:
+		stats.Fired++
+	case alert.StateResolved:
+		stats.Resolved++
+	}
+	if silencedBy != "" {
+		stats.Silenced++
+	}
+	return record
+}
+
+// Snapshot returns firing alerts, up to limit history records (newest
+// first) and per-rule stats, optionally for a single rule.
+func (h *AlertHistory) Snapshot(rule string, limit int) AlertsSnapshot {
+	snapshot := AlertsSnapshot{
+		Firing:  []AlertRecord{},
+		History: []AlertRecord{},
+		Rules:   []AlertRuleStats{},
+	}
+	if h == nil {
+		return snapshot
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count := h.next
+	if h.full {
+		count = len(h.records)
+	}
+	for i := 1; i <= count && (limit <= 0 || len(snapshot.History) < limit); i++ {
+		record := h.records[(h.next-i+len(h.records))%len(h.records)]
+		if rule == "" || record.Rule == rule {
+			snapshot.History = append(snapshot.History, record)
+		}
+	}
+
+	firing := make(map[string]int)
+	for _, record := range h.firing {
+		firing[record.Rule]++
+		if rule == "" || record.Rule == rule {
+			snapshot.Firing = append(snapshot.Firing, record)
+		}
+	}
+	sort.Slice(snapshot.Firing, func(i, j int) bool {
+		return snapshot.Firing[i].ID < snapshot.Firing[j].ID
+	})
+
+	for name, stats := range h.stats {
+		if rule != "" && name != rule {
+			continue
+		}
+		entry := *stats
+		entry.Firing = firing[name]
+		snapshot.Rules = append(snapshot.Rules, entry)
+	}
+	sort.Slice(snapshot.Rules, func(i, j int) bool {
+		return snapshot.Rules[i].Rule < snapshot.Rules[j].Rule
+	})
+	return snapshot
+}
+
+func handleAlerts(w http.ResponseWriter, r *http.Request, history *AlertHistory) {
+	limit := defaultAlertLimit
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	writeJSON(w, history.Snapshot(strings.TrimSpace(r.URL.Query().Get("rule")), limit))
+}
@@ -0,0 +1,446 @@
+package web
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentExt       = ".seg"
+	segmentPartition = "20060102T15"
+	maxSegmentBytes  = 64 * 1024 * 1024
+	recordHeaderSize = 8
+	maxRecordSize    = 16 * 1024 * 1024
+	agePruneInterval = time.Minute
+)
+
+// DiskStore persists events in append-only segment files partitioned by
+// the hour they were written. Each record is framed as
+// [length uint32][crc32 uint32][JSON payload]; a torn or corrupt tail left
+// by a crash is truncated on open from the newest segment, the only one
+// appended to. Corrupt records in older segments are skipped.
+type DiskStore struct {
+	mu         sync.Mutex
+	dir        string
+	maxAge     time.Duration
+	maxBytes   int64
+	segments   []*segment
+	active     *os.File
+	totalBytes int64
+	lastPrune  time.Time
+}
+
+type segment struct {
+	path      string
+	partition string
+	seq       int
+	size      int64
+	count     int
+	minTS     time.Time
+	maxTS     time.Time
+	sources   map[string]struct{}
+}
+
+func OpenDiskStore(dir string, maxAge time.Duration, maxBytes int64) (*DiskStore, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create store dir: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+	sort.Strings(paths)
+
+	store := &DiskStore{dir: dir, maxAge: maxAge, maxBytes: maxBytes}
+	for i, path := range paths {
+		seg, err := recoverSegment(path, i == len(paths)-1)
+		if err != nil {
+			return nil, err
+		}
+		store.segments = append(store.segments, seg)
+		store.totalBytes += seg.size
+	}
+
+	store.mu.Lock()
+	store.pruneLocked(time.Now())
+	store.mu.Unlock()
+	return store, nil
+}
+
+// recoverSegment indexes a segment. The newest one is truncated after its
+// last complete, checksummed record so appends continue from there; older
+// ones are left as they are and their unreadable records reported.
+func recoverSegment(path string, newest bool) (*segment, error) {
+	seg, err := newSegment(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("stat segment: %w", err)
+	}
+	valid, skipped, err := readSegment(file, info.Size(), func(event Event) bool {
+		seg.track(event)
+		return true
+	})
+	_ = file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	seg.size = info.Size()
+	if skipped > 0 {
+		log.Printf("store: skipping %d corrupt records in %s", skipped, path)
+	}
+	if valid < info.Size() {
+		if !newest {
+			log.Printf("store: skipping %d unreadable bytes at the end of %s", info.Size()-valid, path)
+			return seg, nil
+		}
+		log.Printf("store: truncating %s from %d to %d bytes after incomplete record", path, info.Size(), valid)
+		if err := os.Truncate(path, valid); err != nil {
+			return nil, fmt.Errorf("truncate segment: %w", err)
+		}
+		seg.size = valid
+	}
+	return seg, nil
+}
+
+func newSegment(path string) (*segment, error) {
+	name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+	partition, seqValue, ok := strings.Cut(name, "-")
+	var seq int
+	if _, err := fmt.Sscanf(seqValue, "%d", &seq); !ok || err != nil {
+		return nil, fmt.Errorf("invalid segment name %s", filepath.Base(path))
+	}
+	return &segment{
+		path:      path,
+		partition: partition,
+		seq:       seq,
+		sources:   make(map[string]struct{}),
+	}, nil
+}
+
+func (s *segment) track(event Event) {
+	s.count++
+	if s.minTS.IsZero() || event.Timestamp.Before(s.minTS) {
+		s.minTS = event.Timestamp
+	}
+	if event.Timestamp.After(s.maxTS) {
+		s.maxTS = event.Timestamp
+	}
+	s.sources[strings.ToLower(event.Source)] = struct{}{}
+}
+
+func (s *segment) hasAnySource(allowed map[string]struct{}) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for source := range allowed {
+		if _, ok := s.sources[source]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readSegment calls fn for each valid record in the first size bytes and
+// returns the offset just past the last valid one. A complete record with
+// a bad checksum or payload is skipped and counted; an impossible length
+// or a short read ends the scan, since nothing after it can be framed. fn
+// returning false stops the scan early.
+func readSegment(r io.ReaderAt, size int64, fn func(Event) bool) (int64, int, error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(r, 0, size), 256*1024)
+	header := make([]byte, recordHeaderSize)
+	var offset, valid int64
+	skipped := 0
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return valid, skipped, nil
+			}
+			return valid, skipped, fmt.Errorf("read segment: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[0:4])
+		sum := binary.BigEndian.Uint32(header[4:8])
+		if length == 0 || length > maxRecordSize {
+			return valid, skipped, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return valid, skipped, nil
+			}
+			return valid, skipped, fmt.Errorf("read segment: %w", err)
+		}
+		offset += recordHeaderSize + int64(length)
+
+		var event Event
+		if crc32.ChecksumIEEE(payload) != sum || json.Unmarshal(payload, &event) != nil {
+			skipped++
+			continue
+		}
+		valid = offset
+		if !fn(event) {
+			return valid, skipped, nil
+		}
+	}
+}
+
+func (s *DiskStore) Add(event Event) {
+	if s == nil {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("store: marshal event: %v", err)
+		return
+	}
+	// readSegment stops at a record over the limit, so writing one would
+	// hide everything after it.
+	if len(payload) > maxRecordSize {
+		log.Printf("store: dropping %d byte event from %s, over the %d byte record limit", len(payload), event.Source, maxRecordSize)
+		return
+	}
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[recordHeaderSize:], payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	seg, err := s.activeSegmentLocked(now, int64(len(record)))
+	if err != nil {
+		log.Printf("store: %v", err)
+		return
+	}
+	if _, err := s.active.Write(record); err != nil {
+		log.Printf("store: write segment: %v", err)
+		// Drop the segment so the next add starts a fresh one rather than
+		// appending after a partial record.
+		_ = s.active.Close()
+		s.active = nil
+		return
+	}
+	seg.size += int64(len(record))
+	seg.track(event)
+	s.totalBytes += int64(len(record))
+
+	if (s.maxBytes > 0 && s.totalBytes > s.maxBytes) || now.Sub(s.lastPrune) >= agePruneInterval {
+		s.pruneLocked(now)
+	}
+}
+
+func (s *DiskStore) activeSegmentLocked(now time.Time, recordSize int64) (*segment, error) {
+	partition := now.UTC().Format(segmentPartition)
+	var last *segment
+	if len(s.segments) > 0 {
+		last = s.segments[len(s.segments)-1]
+	}
+
+	if s.active != nil && last != nil && last.partition == partition && last.size+recordSize <= maxSegmentBytes {
+		return last, nil
+	}
+	if s.active == nil && last != nil && last.partition == partition && last.size+recordSize <= maxSegmentBytes {
+		file, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open segment: %w", err)
+		}
+		// Discard anything a failed write left behind.
+		if err := file.Truncate(last.size); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("truncate segment: %w", err)
+		}
+		s.active = file
+		return last, nil
+	}
+
+	if s.active != nil {
+		_ = s.active.Close()
+		s.active = nil
+	}
+
+	seq := 0
+	if last != nil && last.partition == partition {
+		seq = last.seq + 1
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf("%s-%04d%s", partition, seq, segmentExt))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("create segment: %w", err)
+	}
+	seg, err := newSegment(path)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	s.active = file
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+// pruneLocked removes whole segments that are past the age limit or, oldest
+// first, exceed the size limit. The segment being written is kept.
+func (s *DiskStore) pruneLocked(now time.Time) {
+	s.lastPrune = now
+	cutoff := time.Time{}
+	if s.maxAge > 0 {
+		cutoff = now.Add(-s.maxAge)
+	}
+
+	kept := s.segments[:0]
+	for i, seg := range s.segments {
+		isActive := s.active != nil && i == len(s.segments)-1
+		expired := !cutoff.IsZero() && seg.maxTS.Before(cutoff)
+		oversize := s.maxBytes > 0 && s.totalBytes > s.maxBytes
+		if !isActive && (expired || oversize) {
+			if err := os.Remove(seg.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("store: remove segment: %v", err)
+				kept = append(kept, seg)
+				continue
+			}
+			s.totalBytes -= seg.size
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	s.segments = kept
+}
+
+func (s *DiskStore) Query(sources []string, since time.Time) []Event {
+	if s == nil {
+		return nil
+	}
+
+	out := make([]Event, 0)
+	s.Scan(sources, since, func(event Event) bool {
+		out = append(out, event)
+		return true
+	})
+	return out
+}
+
+func (s *DiskStore) Scan(sources []string, since time.Time, fn func(Event) bool) {
+	if s == nil {
+		return
+	}
+
+	allowed := sourceSet(sources)
+	if s.maxAge > 0 {
+		if cutoff := time.Now().Add(-s.maxAge); since.Before(cutoff) {
+			since = cutoff
+		}
+	}
+
+	type snapshot struct {
+		file *os.File
+		size int64
+	}
+	s.mu.Lock()
+	snapshots := make([]snapshot, 0, len(s.segments))
+	for _, seg := range s.segments {
+		if seg.count == 0 || seg.maxTS.Before(since) || !seg.hasAnySource(allowed) {
+			continue
+		}
+		// Open under the lock so pruning cannot remove the file first.
+		file, err := os.Open(seg.path)
+		if err != nil {
+			log.Printf("store: open segment: %v", err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot{file: file, size: seg.size})
+	}
+	s.mu.Unlock()
+
+	defer func() {
+		for _, snap := range snapshots {
+			_ = snap.file.Close()
+		}
+	}()
+
+	for _, snap := range snapshots {
+		stopped := false
+		_, _, err := readSegment(snap.file, snap.size, func(event Event) bool {
+			if !since.IsZero() && event.Timestamp.Before(since) {
+				return true
+			}
+			if len(allowed) > 0 {
+				if _, ok := allowed[strings.ToLower(event.Source)]; !ok {
+					return true
+				}
+			}
+			if !fn(event) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if err != nil {
+			log.Printf("store: %v", err)
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// Size returns the bytes currently held on disk.
+func (s *DiskStore) Size() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.totalBytes
+}
+
+func (s *DiskStore) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+func sourceSet(sources []string) map[string]struct{} {
+	allowed := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		if source == "" {
+			continue
+		}
+		allowed[strings.ToLower(source)] = struct{}{}
+	}
+	return allowed
+}
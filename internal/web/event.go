@@ -1,6 +1,10 @@
 package web
 
-import "time"
+import (
+	"time"
+
+	"go-log-aggregator/internal/parse"
+)
 
 type Event struct {
 	Timestamp  time.Time         `json:"timestamp"`
@@ -12,3 +16,35 @@ type Event struct {
 	Fields     map[string]string `json:"fields,omitempty"`
 	Raw        string            `json:"raw,omitempty"`
 }
+
+// Structured converts a stored event back into the form filters evaluate.
+func (e Event) Structured() parse.StructuredEvent {
+	return parse.StructuredEvent{
+		SourceName: e.Source,
+		Format:     e.Format,
+		Timestamp:  e.Timestamp,
+		ReceivedAt: e.ReceivedAt,
+		Severity:   e.Severity,
+		Message:    e.Message,
+		Fields:     e.Fields,
+		Raw:        e.Raw,
+	}
+}
+
+// NewEvent converts a parsed event into its stored form.
+func NewEvent(event parse.StructuredEvent) Event {
+	eventTime := event.Timestamp
+	if eventTime.IsZero() {
+		eventTime = event.ReceivedAt
+	}
+	return Event{
+		Timestamp:  eventTime,
+		ReceivedAt: event.ReceivedAt,
+		Severity:   event.Severity,
+		Message:    event.Message,
+		Source:     event.SourceName,
+		Format:     event.Format,
+		Fields:     event.Fields,
+		Raw:        event.Raw,
+	}
+}
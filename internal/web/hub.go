@@ -4,19 +4,26 @@ import (
 	"context"
 )
 
+// Message is one server-sent event. Log events have no Event name; other
+// kinds (such as "alert") are named so the dashboard can tell them apart.
+type Message struct {
+	Event string
+	Data  []byte
+}
+
 type Hub struct {
-	register   chan chan []byte
-	unregister chan chan []byte
-	broadcast  chan []byte
-	clients    map[chan []byte]struct{}
+	register   chan chan Message
+	unregister chan chan Message
+	broadcast  chan Message
+	clients    map[chan Message]struct{}
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		register:   make(chan chan []byte),
-		unregister: make(chan chan []byte),
-		broadcast:  make(chan []byte, 256),
-		clients:    make(map[chan []byte]struct{}),
+		register:   make(chan chan Message),
+		unregister: make(chan chan Message),
+		broadcast:  make(chan Message, 256),
+		clients:    make(map[chan Message]struct{}),
 	}
 }
 
@@ -35,10 +42,10 @@ func (h *Hub) Run(ctx context.Context) {
 				delete(h.clients, client)
 				close(client)
 			}
-		case payload := <-h.broadcast:
+		case msg := <-h.broadcast:
 			for client := range h.clients {
 				select {
-				case client <- payload:
+				case client <- msg:
 				default:
 				}
 			}
@@ -46,20 +53,26 @@ func (h *Hub) Run(ctx context.Context) {
 	}
 }
 
-func (h *Hub) Register(client chan []byte) {
+func (h *Hub) Register(client chan Message) {
 	h.register <- client
 }
 
-func (h *Hub) Unregister(client chan []byte) {
+func (h *Hub) Unregister(client chan Message) {
 	h.unregister <- client
 }
 
+// Broadcast sends a log event to all clients.
 func (h *Hub) Broadcast(payload []byte) {
-	if payload == nil {
+	h.Publish("", payload)
+}
+
+// Publish sends a named event to all clients.
+func (h *Hub) Publish(event string, payload []byte) {
+	if h == nil || payload == nil {
 		return
 	}
 	select {
-	case h.broadcast <- payload:
+	case h.broadcast <- Message{Event: event, Data: payload}:
 	default:
 	}
 }
@@ -0,0 +1,183 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxIngestBody   = 32 * 1024 * 1024
+	maxIngestErrors = 10
+)
+
+// ErrUnknownSource is returned by an IngestFunc for source names that do
+// not accept pushed lines.
+var ErrUnknownSource = errors.New("unknown ingest source")
+
+// IngestFunc hands decoded lines for source to the processing pipeline.
+type IngestFunc func(ctx context.Context, source, peer string, lines []string) error
+
+type IngestResult struct {
+	Source   string   `json:"source"`
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+func (r *IngestResult) reject(format string, args ...interface{}) {
+	r.Rejected++
+	if len(r.Errors) < maxIngestErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+func handleIngest(w http.ResponseWriter, r *http.Request, ingest IngestFunc) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ingest == nil {
+		http.Error(w, "ingest disabled", http.StatusNotFound)
+		return
+	}
+
+	source := strings.TrimSpace(r.URL.Query().Get("source"))
+	if source == "" {
+		http.Error(w, "source is required", http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxIngestBody)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, "invalid gzip body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		// The decompressed body has the same limit.
+		body = http.MaxBytesReader(w, gz, maxIngestBody)
+	}
+
+	result := IngestResult{Source: source}
+	lines, err := decodeIngestBody(bufio.NewReader(body), &result)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if len(lines) > 0 {
+		if err := ingest(r.Context(), source, r.RemoteAddr, lines); err != nil {
+			status := http.StatusServiceUnavailable
+			if errors.Is(err, ErrUnknownSource) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+	result.Accepted = len(lines)
+	writeJSON(w, result)
+}
+
+// decodeIngestBody accepts a JSON array or NDJSON. Each record is either a
+// JSON string (used verbatim as the line) or a JSON object (used as its
+// compact JSON text); anything else is rejected.
+func decodeIngestBody(reader *bufio.Reader, result *IngestResult) ([]string, error) {
+	first, err := peekNonSpace(reader)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	var lines []string
+	if first == '[' {
+		var records []json.RawMessage
+		if err := json.NewDecoder(reader).Decode(&records); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		for i, record := range records {
+			if line, err := ingestRecord(record); err != nil {
+				result.reject("record %d: %v", i, err)
+			} else {
+				lines = append(lines, line)
+			}
+		}
+		return lines, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if line, err := ingestRecord(text); err != nil {
+			result.reject("line %d: %v", lineNo, err)
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return lines, nil
+}
+
+func ingestRecord(record []byte) (string, error) {
+	record = bytes.TrimSpace(record)
+	if len(record) == 0 {
+		return "", fmt.Errorf("empty record")
+	}
+	switch record[0] {
+	case '"':
+		var line string
+		if err := json.Unmarshal(record, &line); err != nil {
+			return "", fmt.Errorf("invalid JSON string: %w", err)
+		}
+		if strings.TrimSpace(line) == "" {
+			return "", fmt.Errorf("empty record")
+		}
+		return line, nil
+	case '{':
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, record); err != nil {
+			return "", fmt.Errorf("invalid JSON object: %w", err)
+		}
+		return compact.String(), nil
+	default:
+		return "", fmt.Errorf("record must be a JSON string or object")
+	}
+}
+
+func peekNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		return b, reader.UnreadByte()
+	}
+}
@@ -0,0 +1,268 @@
+package web
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/filter"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// SearchRequest selects one page of stored events. Results are ordered by
+// timestamp, with events sharing a timestamp kept in the order they were
+// stored.
+type SearchRequest struct {
+	Query      filter.Node
+	Sources    []string
+	From       time.Time
+	To         time.Time
+	Descending bool
+	Limit      int
+	Cursor     string
+}
+
+type SearchResult struct {
+	Events []Event `json:"events"`
+	// Next is the cursor for the following page; empty on the last page.
+	Next string `json:"next,omitempty"`
+	// Total counts every event matching the request, across all pages.
+	Total int `json:"total"`
+}
+
+// searchCursor identifies the last event of a page by its timestamp and
+// its position in the run of matching events stored with that same
+// timestamp. Events are stored in arrival order, so equal timestamps sit
+// next to each other.
+type searchCursor struct {
+	Timestamp  int64 `json:"t"`
+	Tie        int   `json:"k"`
+	Descending bool  `json:"d,omitempty"`
+}
+
+type searchHit struct {
+	event Event
+	ts    int64
+	tie   int
+}
+
+// hitHeap keeps the first hits of a page in result order. The root is the
+// hit that comes last, so it is the one to drop when a better one arrives.
+type hitHeap struct {
+	hits       []searchHit
+	descending bool
+}
+
+// before reports whether a comes before b in result order.
+func (h *hitHeap) before(a, b searchHit) bool {
+	if h.descending {
+		a, b = b, a
+	}
+	if a.ts != b.ts {
+		return a.ts < b.ts
+	}
+	return a.tie < b.tie
+}
+
+func (h *hitHeap) Len() int           { return len(h.hits) }
+func (h *hitHeap) Less(i, j int) bool { return h.before(h.hits[j], h.hits[i]) }
+func (h *hitHeap) Swap(i, j int)      { h.hits[i], h.hits[j] = h.hits[j], h.hits[i] }
+func (h *hitHeap) Push(x any)         { h.hits = append(h.hits, x.(searchHit)) }
+func (h *hitHeap) Pop() any {
+	last := h.hits[len(h.hits)-1]
+	h.hits = h.hits[:len(h.hits)-1]
+	return last
+}
+
+func Search(store EventStore, req SearchRequest) (SearchResult, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	var cursor *searchCursor
+	if req.Cursor != "" {
+		decoded, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return SearchResult{}, err
+		}
+		if decoded.Descending != req.Descending {
+			return SearchResult{}, fmt.Errorf("cursor was issued for a different sort order")
+		}
+		cursor = &decoded
+	}
+
+	result := SearchResult{Events: []Event{}}
+	if store == nil {
+		return result, nil
+	}
+
+	// One hit past the page tells whether there is a next page.
+	lastTS, tie := int64(0), -1
+	hits := &hitHeap{descending: req.Descending}
+	store.Scan(req.Sources, req.From, func(event Event) bool {
+		if !req.To.IsZero() && event.Timestamp.After(req.To) {
+			return true
+		}
+		if req.Query != nil && !req.Query.Matches(event.Structured()) {
+			return true
+		}
+		ts := event.Timestamp.UnixNano()
+		if ts == lastTS {
+			tie++
+		} else {
+			lastTS, tie = ts, 0
+		}
+		result.Total++
+
+		if cursor != nil && !afterCursor(*cursor, ts, tie) {
+			return true
+		}
+		hit := searchHit{event: event, ts: ts, tie: tie}
+		switch {
+		case hits.Len() <= limit:
+			heap.Push(hits, hit)
+		case hits.before(hit, hits.hits[0]):
+			hits.hits[0] = hit
+			heap.Fix(hits, 0)
+		}
+		return true
+	})
+
+	page := make([]searchHit, hits.Len())
+	for i := len(page) - 1; i >= 0; i-- {
+		page[i] = heap.Pop(hits).(searchHit)
+	}
+	if len(page) > limit {
+		last := page[limit-1]
+		result.Next = encodeCursor(searchCursor{Timestamp: last.ts, Tie: last.tie, Descending: req.Descending})
+		page = page[:limit]
+	}
+	for _, hit := range page {
+		result.Events = append(result.Events, hit.event)
+	}
+	return result, nil
+}
+
+func afterCursor(cursor searchCursor, ts int64, tie int) bool {
+	if cursor.Descending {
+		return ts < cursor.Timestamp || (ts == cursor.Timestamp && tie < cursor.Tie)
+	}
+	return ts > cursor.Timestamp || (ts == cursor.Timestamp && tie > cursor.Tie)
+}
+
+func encodeCursor(cursor searchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (searchCursor, error) {
+	var cursor searchCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request, store EventStore) {
+	req, err := parseSearchRequest(r, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := Search(store, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, result)
+}
+
+func parseSearchRequest(r *http.Request, now time.Time) (SearchRequest, error) {
+	values := r.URL.Query()
+	req := SearchRequest{
+		Descending: true,
+		Cursor:     strings.TrimSpace(values.Get("cursor")),
+	}
+
+	var err error
+	if req.Query, req.Sources, req.From, req.To, err = parseSelection(r, now); err != nil {
+		return SearchRequest{}, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(values.Get("order"))) {
+	case "", "desc":
+	case "asc":
+		req.Descending = false
+	default:
+		return SearchRequest{}, fmt.Errorf("order must be asc or desc")
+	}
+
+	if value := strings.TrimSpace(values.Get("limit")); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return SearchRequest{}, fmt.Errorf("limit must be a positive number")
+		}
+		req.Limit = limit
+	}
+	return req, nil
+}
+
+// parseSelection reads the query, sources, from and to parameters shared by
+// the search and aggregate endpoints.
+func parseSelection(r *http.Request, now time.Time) (filter.Node, []string, time.Time, time.Time, error) {
+	values := r.URL.Query()
+	query, err := parseQuery(r)
+	if err != nil {
+		return nil, nil, time.Time{}, time.Time{}, err
+	}
+	from, err := parseTimeParam(values.Get("from"), now)
+	if err != nil {
+		return nil, nil, time.Time{}, time.Time{}, fmt.Errorf("from: %w", err)
+	}
+	to, err := parseTimeParam(values.Get("to"), now)
+	if err != nil {
+		return nil, nil, time.Time{}, time.Time{}, fmt.Errorf("to: %w", err)
+	}
+	return query, parseList(values.Get("sources")), from, to, nil
+}
+
+// parseTimeParam accepts an RFC 3339 timestamp, "now", "now-15m", or a bare
+// duration such as "15m" meaning that long before now.
+func parseTimeParam(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case value == "now":
+		return now, nil
+	case strings.HasPrefix(value, "now-"):
+		value = strings.TrimPrefix(value, "now-")
+	default:
+		if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return ts, nil
+		}
+	}
+
+	dur, err := time.ParseDuration(value)
+	if err != nil || dur < 0 {
+		return time.Time{}, fmt.Errorf("expected RFC3339 time or relative duration, got %q", value)
+	}
+	return now.Add(-dur), nil
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"go-log-aggregator/internal/filter"
+	"go-log-aggregator/internal/silence"
 )
 
 const dashboardHTML = `<!doctype html>
@@ -25,6 +29,18 @@ const dashboardHTML = `<!doctype html>
     .pill { background: #1f2937; padding: 4px 8px; border-radius: 999px; font-size: 12px; }
     .controls { display: flex; gap: 16px; flex-wrap: wrap; margin: 12px 0; }
     .controls label { font-size: 12px; color: #9ca3af; }
+    .controls input[type=text] { background: #111827; color: #e5e7eb; border: 1px solid #1f2937; padding: 4px 6px; border-radius: 6px; }
+    #chart svg { display: block; width: 100%; height: 120px; background: #111827; border-radius: 6px; }
+    #legend { display: flex; gap: 12px; flex-wrap: wrap; font-size: 12px; color: #9ca3af; margin: 6px 0 12px; }
+    #legend span::before { content: ''; display: inline-block; width: 10px; height: 10px; margin-right: 4px; background: var(--c); }
+    #older { background: #1f2937; color: #e5e7eb; border: 0; padding: 4px 10px; border-radius: 6px; margin-bottom: 8px; cursor: pointer; }
+    #alerts { background: #111827; border: 1px solid #1f2937; border-radius: 6px; padding: 8px 12px; margin-bottom: 12px; font-size: 12px; }
+    #alerts ul { list-style: none; margin: 6px 0; padding: 0; max-height: 160px; overflow-y: auto; }
+    #alerts li { padding: 2px 0; font-family: Consolas, monospace; }
+    #alertStats { color: #9ca3af; }
+    .firing { color: #f87171; }
+    .resolved { color: #34d399; }
+    .silenced { color: #6b7280; }
     .sources { display: flex; gap: 8px; flex-wrap: wrap; }
     .sources label { font-size: 12px; background: #111827; border: 1px solid #1f2937; padding: 4px 8px; border-radius: 6px; }
   </style>
@@ -49,8 +65,28 @@ const dashboardHTML = `<!doctype html>
           <option value="168h">last 1 week</option>
         </select>
       </label>
+      <label>
+        Query
+        <input id="query" type="text" size="48" placeholder="source:nginx AND status>=500" />
+      </label>
+      <label>
+        Chart by
+        <select id="groupBy">
+          <option value="severity">severity</option>
+          <option value="source">source</option>
+        </select>
+      </label>
       <div class="sources" id="sources"></div>
     </div>
+    <section id="alerts">
+      <div class="row"><strong>Alerts</strong><span class="pill" id="alertSummary">none firing</span></div>
+      <ul id="firing"></ul>
+      <div id="alertStats"></div>
+      <ul id="alertHistory"></ul>
+    </section>
+    <div id="chart"></div>
+    <div id="legend"></div>
+    <button id="older" hidden>load older</button>
     <div id="log"></div>
   </main>
   <script>
@@ -58,8 +94,25 @@ const dashboardHTML = `<!doctype html>
     const log = document.getElementById('log');
     const windowSelect = document.getElementById('window');
     const sourcesWrap = document.getElementById('sources');
+    const queryInput = document.getElementById('query');
+    const olderButton = document.getElementById('older');
+    const groupBySelect = document.getElementById('groupBy');
+    const chart = document.getElementById('chart');
+    const legend = document.getElementById('legend');
+    const firingList = document.getElementById('firing');
+    const alertHistoryList = document.getElementById('alertHistory');
+    const alertSummary = document.getElementById('alertSummary');
+    const alertStats = document.getElementById('alertStats');
+    const alertHistorySize = 50;
+    const pageSize = 500;
+    const palette = ['#60a5fa', '#34d399', '#fbbf24', '#f87171', '#a78bfa', '#f472b6', '#22d3ee', '#a3e635', '#fb923c', '#94a3b8', '#6b7280'];
+    const severityColors = { critical: '#a78bfa', error: '#f87171', warn: '#fbbf24', warning: '#fbbf24', info: '#60a5fa', debug: '#94a3b8' };
+    let nextCursor = '';
     let selectedSources = new Set();
     let stream;
+    let firingAlerts = new Map();
+    let alertRecords = [];
+    let ruleStats = {};
 
     function formatEvent(event) {
       const ts = event.timestamp || event.received_at || '';
@@ -73,6 +126,10 @@ const dashboardHTML = `<!doctype html>
       log.scrollTop = log.scrollHeight;
     }
 
+    function currentQuery() {
+      return queryInput.value.trim();
+    }
+
     function currentWindow() {
       return windowSelect.value;
     }
@@ -109,27 +166,193 @@ const dashboardHTML = `<!doctype html>
         });
     }
 
-    function refreshHistory() {
-      log.textContent = '';
+    function searchParams() {
       const params = new URLSearchParams();
-      params.set('window', currentWindow());
+      params.set('from', currentWindow());
+      params.set('order', 'desc');
+      params.set('limit', pageSize);
       const sources = selectedSourcesList();
       if (sources.length > 0) {
         params.set('sources', sources.join(','));
       }
-      fetch('/api/events?' + params.toString())
-        .then(res => res.json())
-        .then(events => {
-          events.forEach(event => appendLine(formatEvent(event)));
+      if (currentQuery()) {
+        params.set('query', currentQuery());
+      }
+      return params;
+    }
+
+    // loadPage fetches one page, newest first, and prepends it in
+    // chronological order above what is already shown.
+    function loadPage(cursor) {
+      const params = searchParams();
+      if (cursor) {
+        params.set('cursor', cursor);
+      }
+      return fetch('/api/search?' + params.toString())
+        .then(res => {
+          if (!res.ok) {
+            return res.text().then(text => { throw new Error(text); });
+          }
+          return res.json();
+        })
+        .then(page => {
+          const lines = page.events.slice().reverse().map(formatEvent);
+          if (lines.length > 0) {
+            log.textContent = lines.join("\n") + "\n" + log.textContent;
+          }
+          nextCursor = page.next || '';
+          olderButton.hidden = !nextCursor;
+          olderButton.textContent = 'load older (' + page.total + ' matching)';
+        })
+        .catch(err => { status.textContent = err.message; });
+    }
+
+    function groupColor(group, index) {
+      if (groupBySelect.value === 'severity' && severityColors[group]) {
+        return severityColors[group];
+      }
+      return palette[index % palette.length];
+    }
+
+    // refreshChart draws event counts per interval as stacked bars, one
+    // color per group.
+    function refreshChart() {
+      const params = searchParams();
+      params.delete('order');
+      params.delete('limit');
+      params.set('groupBy', groupBySelect.value);
+      fetch('/api/aggregate?' + params.toString())
+        .then(res => res.ok ? res.json() : null)
+        .then(result => {
+          if (!result) {
+            return;
+          }
+          const groups = result.groups || [];
+          const max = Math.max(1, ...result.buckets.map(b => b.count));
+          const width = 1000;
+          const height = 120;
+          const barWidth = width / Math.max(1, result.buckets.length);
+          let bars = '';
+          result.buckets.forEach((bucket, i) => {
+            let y = height;
+            groups.forEach((group, g) => {
+              const n = (bucket.groups || {})[group] || 0;
+              if (!n) {
+                return;
+              }
+              const h = n / max * (height - 4);
+              y -= h;
+              bars += '<rect x="' + (i * barWidth + 1) + '" y="' + y + '" width="' + Math.max(1, barWidth - 2) +
+                '" height="' + h + '" fill="' + groupColor(group, g) + '"><title>' +
+                new Date(bucket.start).toLocaleTimeString() + ' ' + escapeHTML(group) + ': ' + n + '</title></rect>';
+            });
+          });
+          chart.innerHTML = '<svg viewBox="0 0 ' + width + ' ' + height + '" preserveAspectRatio="none">' + bars + '</svg>';
+          legend.innerHTML = groups.map((group, g) =>
+            '<span style="--c:' + groupColor(group, g) + '">' + escapeHTML(group) + '</span>').join('') +
+            '<span style="--c:transparent">' + result.total + ' events, ' + result.interval + ' buckets</span>';
         });
     }
 
+    function escapeHTML(value) {
+      return String(value).replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' })[c]);
+    }
+
+    function alertKey(record) {
+      return record.rule + '\u0000' + (record.group || '');
+    }
+
+    function formatAlert(record) {
+      let text = new Date(record.time).toLocaleTimeString() + ' ' + (record.state || 'alert').toUpperCase() + ' ' + record.rule;
+      if (record.group) {
+        text += ' group=' + record.group;
+      }
+      if (record.window) {
+        text += ' value=' + record.value + ' threshold=' + record.threshold + ' window=' + record.window;
+      } else if (record.count > 1) {
+        text += ' count=' + record.count;
+      }
+      const event = (record.events || [])[0];
+      if (event) {
+        text += ' [' + event.source + '] ' + (event.message || event.raw || '');
+      }
+      if (record.silencedBy) {
+        text += ' (silenced by ' + record.silencedBy + ')';
+      }
+      return text;
+    }
+
+    function alertItem(record) {
+      const cls = record.silencedBy ? 'silenced' : (record.state === 'resolved' ? 'resolved' : 'firing');
+      return '<li class="' + cls + '">' + escapeHTML(formatAlert(record)) + '</li>';
+    }
+
+    function renderAlerts() {
+      const firing = Array.from(firingAlerts.values());
+      alertSummary.textContent = firing.length ? firing.length + ' firing' : 'none firing';
+      firingList.innerHTML = firing.map(alertItem).join('');
+      alertStats.textContent = Object.values(ruleStats).map(stats =>
+        stats.rule + ': ' + stats.alerts + ' alerts, ' + stats.fired + ' fired, ' + stats.resolved + ' resolved, ' + stats.silenced + ' silenced'
+      ).join(' | ');
+      alertHistoryList.innerHTML = alertRecords.map(alertItem).join('');
+    }
+
+    function loadAlerts() {
+      fetch('/api/alerts?limit=' + alertHistorySize)
+        .then(res => res.ok ? res.json() : null)
+        .then(snapshot => {
+          if (!snapshot) {
+            return;
+          }
+          firingAlerts = new Map(snapshot.firing.map(record => [alertKey(record), record]));
+          alertRecords = snapshot.history;
+          ruleStats = {};
+          snapshot.rules.forEach(stats => { ruleStats[stats.rule] = stats; });
+          renderAlerts();
+        });
+    }
+
+    // applyAlert folds one alert pushed over the stream into the panel.
+    function applyAlert(record) {
+      if (record.state === 'firing') {
+        firingAlerts.set(alertKey(record), record);
+      } else if (record.state === 'resolved') {
+        firingAlerts.delete(alertKey(record));
+      }
+      alertRecords.unshift(record);
+      alertRecords = alertRecords.slice(0, alertHistorySize);
+      const stats = ruleStats[record.rule] || (ruleStats[record.rule] = { rule: record.rule, alerts: 0, fired: 0, resolved: 0, silenced: 0 });
+      stats.alerts++;
+      if (record.state === 'firing') stats.fired++;
+      if (record.state === 'resolved') stats.resolved++;
+      if (record.silencedBy) stats.silenced++;
+      renderAlerts();
+    }
+
+    function refreshHistory() {
+      refreshChart();
+      log.textContent = '';
+      nextCursor = '';
+      loadPage('').then(() => { log.scrollTop = log.scrollHeight; });
+    }
+
     function openStream() {
       if (stream) {
         stream.close();
       }
-      stream = new EventSource('/stream');
-      stream.onopen = () => { status.textContent = 'connected'; };
+      const query = currentQuery();
+      stream = new EventSource(query ? '/stream?query=' + encodeURIComponent(query) : '/stream');
+      stream.onopen = () => {
+        status.textContent = 'connected';
+        loadAlerts();
+      };
+      stream.addEventListener('alert', (evt) => {
+        try {
+          applyAlert(JSON.parse(evt.data));
+        } catch (err) {
+          loadAlerts();
+        }
+      });
       stream.onerror = () => { status.textContent = 'disconnected'; };
       stream.onmessage = (evt) => {
         try {
@@ -161,10 +384,27 @@ const dashboardHTML = `<!doctype html>
       return 0;
     }
 
+    groupBySelect.addEventListener('change', () => {
+      refreshChart();
+    });
+
+    setInterval(refreshChart, 10000);
+
+    olderButton.addEventListener('click', () => {
+      if (nextCursor) {
+        loadPage(nextCursor);
+      }
+    });
+
     windowSelect.addEventListener('change', () => {
       refreshHistory();
     });
 
+    queryInput.addEventListener('change', () => {
+      refreshHistory();
+      openStream();
+    });
+
     loadSources().then(() => {
       refreshHistory();
       openStream();
@@ -173,20 +413,56 @@ const dashboardHTML = `<!doctype html>
 </body>
 </html>`
 
-func StartServer(ctx context.Context, addr string, hub *Hub, store *Store, sources []string) error {
+func StartServer(ctx context.Context, addr string, hub *Hub, store EventStore, sources SourcesFunc, ingest IngestFunc, silences *silence.Store, alerts *AlertHistory) error {
 	if addr == "" {
 		return fmt.Errorf("http address is required")
 	}
 
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           NewHandler(hub, store, sources, ingest, silences, alerts),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("http shutdown: %v", err)
+		}
+	}()
+
+	log.Printf("dashboard listening on http://%s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// SourcesFunc lists the configured source names; it is called per request
+// so the list follows config reloads.
+type SourcesFunc func() []string
+
+func NewHandler(hub *Hub, store EventStore, sources SourcesFunc, ingest IngestFunc, silences *silence.Store, alerts *AlertHistory) http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		_, _ = w.Write([]byte(dashboardHTML))
 	})
 	mux.HandleFunc("/api/sources", func(w http.ResponseWriter, r *http.Request) {
-		writeJSON(w, sources)
+		var names []string
+		if sources != nil {
+			names = sources()
+		}
+		writeJSON(w, names)
 	})
 	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		query, err := parseQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if store == nil {
 			writeJSON(w, []Event{})
 			return
@@ -198,34 +474,64 @@ func StartServer(ctx context.Context, addr string, hub *Hub, store *Store, sourc
 				since = time.Now().Add(-dur)
 			}
 		}
-		sourceList := parseSources(r.URL.Query().Get("sources"))
-		events := store.Query(sourceList, since)
-		writeJSON(w, events)
+		limit := maxRecentEvents
+		if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxRecentEvents)
+		}
+		writeJSON(w, recentEvents(store, parseList(r.URL.Query().Get("sources")), since, query, limit))
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		handleSearch(w, r, store)
+	})
+	mux.HandleFunc("/api/aggregate", func(w http.ResponseWriter, r *http.Request) {
+		handleAggregate(w, r, store)
+	})
+	mux.HandleFunc("/api/ingest", func(w http.ResponseWriter, r *http.Request) {
+		handleIngest(w, r, ingest)
+	})
+	mux.HandleFunc("/api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		handleAlerts(w, r, alerts)
+	})
+	mux.HandleFunc("/api/silences", func(w http.ResponseWriter, r *http.Request) {
+		handleSilences(w, r, silences)
+	})
+	mux.HandleFunc("/api/silences/", func(w http.ResponseWriter, r *http.Request) {
+		handleSilence(w, r, silences)
 	})
 	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
 		stream(w, r, hub)
 	})
+	return mux
+}
 
-	server := &http.Server{
-		Addr:              addr,
-		Handler:           mux,
-		ReadHeaderTimeout: 5 * time.Second,
-	}
+// maxRecentEvents caps /api/events; older matches need /api/search.
+const maxRecentEvents = 10000
 
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			log.Printf("http shutdown: %v", err)
+// recentEvents returns the newest limit events matching query, oldest
+// first, holding no more than that many while scanning.
+func recentEvents(store EventStore, sources []string, since time.Time, query filter.Node, limit int) []Event {
+	ring := make([]Event, 0, min(limit, 1024))
+	next := 0
+	store.Scan(sources, since, func(event Event) bool {
+		if query != nil && !query.Matches(event.Structured()) {
+			return true
 		}
-	}()
-
-	log.Printf("dashboard listening on http://%s", addr)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		return err
-	}
-	return nil
+		if len(ring) < limit {
+			ring = append(ring, event)
+			return true
+		}
+		ring[next] = event
+		next = (next + 1) % limit
+		return true
+	})
+	out := make([]Event, 0, len(ring))
+	out = append(out, ring[next:]...)
+	return append(out, ring[:next]...)
 }
 
 func stream(w http.ResponseWriter, r *http.Request, hub *Hub) {
@@ -234,12 +540,17 @@ func stream(w http.ResponseWriter, r *http.Request, hub *Hub) {
 		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
 		return
 	}
+	query, err := parseQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	client := make(chan []byte, 32)
+	client := make(chan Message, 32)
 	hub.Register(client)
 	defer hub.Unregister(client)
 
@@ -250,17 +561,37 @@ func stream(w http.ResponseWriter, r *http.Request, hub *Hub) {
 		select {
 		case <-r.Context().Done():
 			return
-		case payload, ok := <-client:
+		case msg, ok := <-client:
 			if !ok {
 				return
 			}
-			_, _ = fmt.Fprintf(w, "data: %s\n\n", payload)
+			if msg.Event != "" {
+				_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, msg.Data)
+				flusher.Flush()
+				continue
+			}
+			if query != nil {
+				var event Event
+				if err := json.Unmarshal(msg.Data, &event); err != nil || !query.Matches(event.Structured()) {
+					continue
+				}
+			}
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", msg.Data)
 			flusher.Flush()
 		}
 	}
 }
 
-func parseSources(value string) []string {
+func parseQuery(r *http.Request) (filter.Node, error) {
+	value := strings.TrimSpace(r.URL.Query().Get("query"))
+	if value == "" {
+		return nil, nil
+	}
+	return filter.ParseQuery(value)
+}
+
+// parseList splits a comma-separated parameter, dropping empty items.
+func parseList(value string) []string {
 	if value == "" {
 		return nil
 	}
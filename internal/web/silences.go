@@ -0,0 +1,67 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-log-aggregator/internal/silence"
+)
+
+const maxSilenceBody = 64 * 1024
+
+// handleSilences lists (GET) and creates (POST) silences.
+func handleSilences(w http.ResponseWriter, r *http.Request, silences *silence.Store) {
+	if silences == nil {
+		http.Error(w, "silences disabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, silences.List(time.Now()))
+	case http.MethodPost:
+		var req silence.Silence
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSilenceBody)).Decode(&req); err != nil {
+			http.Error(w, "invalid silence: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, err := silences.Add(req, time.Now())
+		if errors.Is(err, silence.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, created)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSilence expires the silence named in the path on DELETE.
+func handleSilence(w http.ResponseWriter, r *http.Request, silences *silence.Store) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/silences/")
+	expired, err := silences.Expire(id, time.Now())
+	if errors.Is(err, silence.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, expired)
+}
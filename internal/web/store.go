@@ -6,6 +6,16 @@ import (
 	"time"
 )
 
+// EventStore is the history behind the dashboard API. Store keeps it in
+// memory; DiskStore persists it across restarts.
+type EventStore interface {
+	Add(event Event)
+	Query(sources []string, since time.Time) []Event
+	// Scan calls fn for each event from sources at or after since, in the
+	// order they were added, until fn returns false.
+	Scan(sources []string, since time.Time, fn func(Event) bool)
+}
+
 type Store struct {
 	mu        sync.RWMutex
 	maxAge    time.Duration
@@ -41,18 +51,23 @@ func (s *Store) Query(sources []string, since time.Time) []Event {
 		return nil
 	}
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	out := make([]Event, 0)
+	s.Scan(sources, since, func(event Event) bool {
+		out = append(out, event)
+		return true
+	})
+	return out
+}
 
-	allowed := make(map[string]struct{}, len(sources))
-	for _, source := range sources {
-		if source == "" {
-			continue
-		}
-		allowed[strings.ToLower(source)] = struct{}{}
+func (s *Store) Scan(sources []string, since time.Time, fn func(Event) bool) {
+	if s == nil {
+		return
 	}
 
-	out := make([]Event, 0, len(s.events))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	allowed := sourceSet(sources)
 	for _, event := range s.events {
 		if !since.IsZero() && event.Timestamp.Before(since) {
 			continue
@@ -62,9 +77,10 @@ func (s *Store) Query(sources []string, since time.Time) []Event {
 				continue
 			}
 		}
-		out = append(out, event)
+		if !fn(event) {
+			return
+		}
 	}
-	return out
 }
 
 func (s *Store) pruneLocked() {
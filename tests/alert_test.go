@@ -1,7 +1,9 @@
 package tests
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"go-log-aggregator/internal/alert"
 	"go-log-aggregator/internal/config"
@@ -38,3 +40,222 @@ func TestEvaluatorInvalidPattern(t *testing.T) {
 		t.Fatalf("expected error for invalid pattern")
 	}
 }
+
+func TestEvaluatorReportsEveryInvalidRule(t *testing.T) {
+	_, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "bad-pattern", Pattern: "("},
+		{Name: "ok", Pattern: "panic"},
+		{Name: "bad-window", Type: "threshold", Pattern: "x", Window: "soon"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "bad-pattern") || !strings.Contains(err.Error(), "bad-window") {
+		t.Fatalf("expected both invalid rules reported, got %v", err)
+	}
+}
+
+func TestThresholdAlertFiresAndResolves(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "panics", Type: "threshold", Pattern: "panic", Window: "1m", Threshold: 2, GroupBy: "host"},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	base := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	event := func(offset time.Duration, host string) parse.StructuredEvent {
+		return parse.StructuredEvent{
+			Message:    "panic: nil map",
+			Fields:     map[string]string{"host": host},
+			ReceivedAt: base.Add(offset),
+		}
+	}
+
+	var fired []alert.Match
+	for i, ev := range []parse.StructuredEvent{
+		event(0, "a"), event(time.Second, "a"), event(2*time.Second, "b"), event(3*time.Second, "a"), event(4*time.Second, "a"),
+	} {
+		matches := eval.Evaluate(ev)
+		if i < 3 && len(matches) != 0 {
+			t.Fatalf("event %d: unexpected matches %+v", i, matches)
+		}
+		fired = append(fired, matches...)
+	}
+	if len(fired) != 1 || fired[0].State != alert.StateFiring || fired[0].Group != "a" || fired[0].Count != 3 {
+		t.Fatalf("expected one firing for host a, got %+v", fired)
+	}
+
+	if matches := eval.Tick(base.Add(30 * time.Second)); len(matches) != 0 {
+		t.Fatalf("expected still firing, got %+v", matches)
+	}
+	matches := eval.Tick(base.Add(2 * time.Minute))
+	if len(matches) != 1 || matches[0].State != alert.StateResolved || matches[0].Group != "a" || matches[0].Count != 0 {
+		t.Fatalf("expected resolve, got %+v", matches)
+	}
+	if matches := eval.Tick(base.Add(3 * time.Minute)); len(matches) != 0 {
+		t.Fatalf("expected no repeat resolve, got %+v", matches)
+	}
+}
+
+func TestThresholdAlertCountsEventTimestamps(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "panics", Type: "threshold", Pattern: "panic", Window: "1m", Threshold: 2},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	now := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	event := func(age time.Duration) parse.StructuredEvent {
+		return parse.StructuredEvent{Message: "panic: nil map", Timestamp: now.Add(-age), ReceivedAt: now}
+	}
+
+	// Backfilled history from an hour ago is outside the window.
+	for i := 0; i < 5; i++ {
+		if matches := eval.Evaluate(event(time.Hour)); len(matches) != 0 {
+			t.Fatalf("backfilled event %d: unexpected matches %+v", i, matches)
+		}
+	}
+
+	var fired []alert.Match
+	for _, age := range []time.Duration{10 * time.Second, 30 * time.Second, 20 * time.Second} {
+		fired = append(fired, eval.Evaluate(event(age))...)
+	}
+	if len(fired) != 1 || fired[0].State != alert.StateFiring || fired[0].Count != 3 {
+		t.Fatalf("expected one firing for recent events, got %+v", fired)
+	}
+	// The oldest of them leaves the window first.
+	if matches := eval.Tick(now.Add(35 * time.Second)); len(matches) != 1 || matches[0].State != alert.StateResolved {
+		t.Fatalf("expected resolve once the oldest event expires, got %+v", matches)
+	}
+}
+
+func TestRatioAlert(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "5xx", Type: "ratio", Query: "status>=500", SourceName: "nginx", Window: "1m", Threshold: 0.5, MinEvents: 4},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	base := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	var fired []alert.Match
+	for i, status := range []string{"500", "502", "503", "200", "200", "200"} {
+		fired = append(fired, eval.Evaluate(parse.StructuredEvent{
+			SourceName: "nginx",
+			Fields:     map[string]string{"status": status},
+			ReceivedAt: base.Add(time.Duration(i) * time.Second),
+		})...)
+	}
+	// 3/4 at the fourth event fires; 3/6 at the sixth resolves.
+	if len(fired) != 2 || fired[0].State != alert.StateFiring || fired[0].Value != 0.75 || fired[1].State != alert.StateResolved {
+		t.Fatalf("unexpected ratio matches: %+v", fired)
+	}
+
+	if _, err := alert.NewEvaluator([]config.AlertRule{{Name: "bad", Type: "ratio", Pattern: "x", Window: "1m", Threshold: 2}}); err == nil {
+		t.Fatalf("expected error for ratio threshold above 1")
+	}
+	if _, err := alert.NewEvaluator([]config.AlertRule{{Name: "bad", Type: "threshold", Pattern: "x"}}); err == nil {
+		t.Fatalf("expected error for missing window")
+	}
+}
+
+func TestAbsenceAlert(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "quiet", Type: "absence", SourceName: "app", Window: "1m", Grace: "2m"},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	base := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	if matches := eval.Tick(base.Add(time.Hour)); len(matches) != 0 {
+		t.Fatalf("expected nothing before start, got %+v", matches)
+	}
+
+	eval.Start(base)
+	if matches := eval.Tick(base.Add(90 * time.Second)); len(matches) != 0 {
+		t.Fatalf("expected grace period, got %+v", matches)
+	}
+	matches := eval.Tick(base.Add(2 * time.Minute))
+	if len(matches) != 1 || matches[0].State != alert.StateFiring || matches[0].RuleName != "quiet" {
+		t.Fatalf("expected firing after grace, got %+v", matches)
+	}
+	if matches := eval.Tick(base.Add(3 * time.Minute)); len(matches) != 0 {
+		t.Fatalf("expected no repeat firing, got %+v", matches)
+	}
+
+	other := parse.StructuredEvent{SourceName: "worker", ReceivedAt: base.Add(4 * time.Minute)}
+	if matches := eval.Evaluate(other); len(matches) != 0 {
+		t.Fatalf("unexpected matches for other source: %+v", matches)
+	}
+	matches = eval.Evaluate(parse.StructuredEvent{SourceName: "app", ReceivedAt: base.Add(4 * time.Minute)})
+	if len(matches) != 1 || matches[0].State != alert.StateResolved {
+		t.Fatalf("expected resolve on traffic, got %+v", matches)
+	}
+
+	if matches := eval.Tick(base.Add(4*time.Minute + 30*time.Second)); len(matches) != 0 {
+		t.Fatalf("expected quiet within window, got %+v", matches)
+	}
+	if matches := eval.Tick(base.Add(5 * time.Minute)); len(matches) != 1 || matches[0].State != alert.StateFiring {
+		t.Fatalf("expected firing again, got %+v", matches)
+	}
+}
+
+func TestAlertCooldownAndDedupKey(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "errors", Pattern: "error", Cooldown: "1m", DedupKey: []string{"source"}},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := func(source string, offset time.Duration) parse.StructuredEvent {
+		return parse.StructuredEvent{SourceName: source, Message: "error", Raw: "error", ReceivedAt: start.Add(offset)}
+	}
+
+	if got := eval.Evaluate(event("api", 0)); len(got) != 1 || got[0].Group != "api" {
+		t.Fatalf("expected first match to notify, got %+v", got)
+	}
+	if got := eval.Evaluate(event("api", 10*time.Second)); len(got) != 0 {
+		t.Fatalf("expected repeat to be suppressed, got %+v", got)
+	}
+	if got := eval.Evaluate(event("web", 20*time.Second)); len(got) != 1 {
+		t.Fatalf("expected a different dedup key to notify, got %+v", got)
+	}
+	eval.Tick(start.Add(30 * time.Second))
+	got := eval.Evaluate(event("api", 70*time.Second))
+	if len(got) != 1 || got[0].Suppressed != 1 {
+		t.Fatalf("expected notification after cooldown reporting 1 suppressed, got %+v", got)
+	}
+
+	if _, err := alert.NewEvaluator([]config.AlertRule{{Name: "bad", Pattern: "x", Cooldown: "soon"}}); err == nil {
+		t.Fatalf("expected error for invalid cooldown")
+	}
+}
+
+func TestAlertGroupWait(t *testing.T) {
+	eval, err := alert.NewEvaluator([]config.AlertRule{
+		{Name: "errors", Pattern: "error", GroupWait: "30s", MaxSamples: 2},
+	})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		event := parse.StructuredEvent{Message: "error", Raw: "error", ReceivedAt: start.Add(time.Duration(i) * time.Second)}
+		if got := eval.Evaluate(event); len(got) != 0 {
+			t.Fatalf("expected matches to be held while grouping, got %+v", got)
+		}
+	}
+	if got := eval.Tick(start.Add(10 * time.Second)); len(got) != 0 {
+		t.Fatalf("expected no notification before groupWait, got %+v", got)
+	}
+	got := eval.Tick(start.Add(30 * time.Second))
+	if len(got) != 1 || got[0].Count != 4 || len(got[0].Samples) != 2 {
+		t.Fatalf("expected one grouped notification with 4 matches and 2 samples, got %+v", got)
+	}
+	if got := eval.Tick(start.Add(time.Minute)); len(got) != 0 {
+		t.Fatalf("expected group to be sent once, got %+v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/checkpoint"
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/ingest"
+)
+
+func TestCheckpointResumeAndReplace(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	storePath := filepath.Join(dir, "checkpoints.json")
+
+	if err := os.WriteFile(logPath, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	store, err := checkpoint.Open(storePath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	id, err := checkpoint.Identify(file)
+	_ = file.Close()
+	if err != nil {
+		t.Fatalf("identify: %v", err)
+	}
+	store.Track(logPath, id, 0)
+	store.Commit(logPath, id, 6)
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	reopened, err := checkpoint.Open(storePath)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	file, err = os.Open(logPath)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	offset, ok := reopened.Resume(logPath, file)
+	_ = file.Close()
+	if !ok || offset != 6 {
+		t.Fatalf("expected resume at 6, got %d (ok=%v)", offset, ok)
+	}
+
+	if err := os.Remove(logPath); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := os.WriteFile(logPath, []byte("other\ncontent\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	file, err = os.Open(logPath)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	offset, ok = reopened.Resume(logPath, file)
+	_ = file.Close()
+	if !ok || offset != 0 {
+		t.Fatalf("expected replaced file to restart at 0, got %d (ok=%v)", offset, ok)
+	}
+}
+
+func TestTailerResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	storePath := filepath.Join(dir, "checkpoints.json")
+	source := config.Source{Name: "app", Path: logPath, Format: "json"}
+
+	if err := os.WriteFile(logPath, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	store, err := checkpoint.Open(storePath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan ingest.Event, 16)
+	if err := ingest.StartTailer(ctx, source, store, events, nil); err != nil {
+		t.Fatalf("start tailer: %v", err)
+	}
+	appendLine(t, logPath, "first")
+	event := waitEvent(t, events)
+	if event.Line != "first" {
+		t.Fatalf("expected first, got %q", event.Line)
+	}
+	store.Commit(event.SourcePath, event.FileID, event.Offset)
+	cancel()
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	appendLine(t, logPath, "while-down")
+
+	store, err = checkpoint.Open(storePath)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	events = make(chan ingest.Event, 16)
+	if err := ingest.StartTailer(ctx, source, store, events, nil); err != nil {
+		t.Fatalf("restart tailer: %v", err)
+	}
+	event = waitEvent(t, events)
+	if event.Line != "while-down" {
+		t.Fatalf("expected resume with while-down, got %q", event.Line)
+	}
+}
+
+func appendLine(t *testing.T, path, line string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatalf("open for append: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+}
+
+func waitEvent(t *testing.T, events <-chan ingest.Event) ingest.Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+	return ingest.Event{}
+}
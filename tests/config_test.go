@@ -3,6 +3,7 @@ package tests
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"go-log-aggregator/internal/config"
@@ -62,3 +63,271 @@ func TestLoadConfigInvalid(t *testing.T) {
 		t.Fatalf("expected error for missing fields")
 	}
 }
+
+func TestLoadConfigSyslogSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	data := `{"sources":[{"name":"net","type":"syslog","address":":5514","protocol":"udp"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	if cfg.Sources[0].Format != "syslog" {
+		t.Fatalf("expected syslog format by default, got %q", cfg.Sources[0].Format)
+	}
+
+	data = `{"sources":[{"name":"net","type":"syslog","format":"syslog"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := config.Load(path); err == nil {
+		t.Fatalf("expected error for syslog source without address")
+	}
+}
+
+func TestLoadYAMLWithIncludesAndEnv(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	t.Setenv("LOG_DIR", "/var/log/app")
+	t.Setenv("HOOK_URL", "")
+
+	write("config.yaml", `
+checkpointPath: data/checkpoints.json
+include:
+  - conf.d/*.yaml
+  - extra.yml
+sources:
+  - name: app
+    path: ${LOG_DIR}/app.log
+    format: json
+alerts:
+  - name: panic
+    pattern: panic\s+\w+
+    notify:
+      - type: webhook
+        url: ${HOOK_URL:-http://localhost:9000/hook}
+`)
+	write("conf.d/20-nginx.yaml", `
+sources:
+  - name: nginx
+    path: /var/log/nginx/access.log
+    format: nginx
+`)
+	write("conf.d/10-db.yaml", `
+sources:
+  - {name: db, path: "$${LOG_DIR}/db.log", format: plain}
+checkpointPath: data/db-checkpoints.json
+`)
+	write("extra.yml", "alerts:\n  - {name: errors, type: threshold, pattern: error, window: 1m, threshold: 5}\n")
+
+	cfg, err := config.Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	var names []string
+	for _, src := range cfg.Sources {
+		names = append(names, src.Name)
+	}
+	if strings.Join(names, ",") != "app,db,nginx" {
+		t.Fatalf("expected sources in include order, got %v", names)
+	}
+	if cfg.Sources[0].Path != "/var/log/app/app.log" || cfg.Sources[1].Path != "${LOG_DIR}/db.log" {
+		t.Fatalf("unexpected expansion: %q, %q", cfg.Sources[0].Path, cfg.Sources[1].Path)
+	}
+	if len(cfg.Alerts) != 2 || cfg.Alerts[0].Pattern != `panic\s+\w+` || cfg.Alerts[1].Threshold != 5 {
+		t.Fatalf("unexpected alerts: %+v", cfg.Alerts)
+	}
+	if cfg.Alerts[0].Notify[0].URL != "http://localhost:9000/hook" {
+		t.Fatalf("expected default for empty variable, got %q", cfg.Alerts[0].Notify[0].URL)
+	}
+	if cfg.CheckpointPath != "data/db-checkpoints.json" {
+		t.Fatalf("expected the last file's checkpointPath, got %q", cfg.CheckpointPath)
+	}
+	if len(cfg.Files) != 5 {
+		t.Fatalf("expected config, includes and glob in Files, got %v", cfg.Files)
+	}
+}
+
+func TestLoadConfigErrorsPointToKey(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "wrong type",
+			files: map[string]string{"config.json": `{"alerts":[{"name":"a"},{"name":"b","threshold":"high"}]}`},
+			want:  "config.json: alerts[1].threshold: expected a number, got a string",
+		},
+		{
+			name: "invalid entry in include",
+			files: map[string]string{
+				"config.yaml":       "include: conf.d/*.yaml\nsources:\n  - {name: app, path: a.log, format: json}\n",
+				"conf.d/nginx.yaml": "sources:\n  - {name: ok, path: b.log, format: nginx}\n  - {name: nginx, format: nginx}\n",
+			},
+			want: filepath.Join("conf.d", "nginx.yaml") + ": sources[1].path is required",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"config.yaml": "include: other.yaml\n",
+				"other.yaml":  "include: config.yaml\n",
+			},
+			want: "config.yaml is included more than once",
+		},
+	}
+
+	for i, tc := range cases {
+		root := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "-"))
+		var main string
+		for name, data := range tc.files {
+			path := filepath.Join(root, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+			if strings.HasPrefix(name, "config.") {
+				main = path
+			}
+		}
+		_, err := config.Load(main)
+		if err == nil || !strings.HasSuffix(err.Error(), tc.want) {
+			t.Fatalf("case %d (%s): expected error ending in %q, got %v", i, tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestLoadConfigWarnsOnUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "sources:\n  - name: app\n    path: a.log\n    format: json\n    fromat: json\nnewSetting: 1\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("expected unknown keys not to fail loading, got %v", err)
+	}
+	if len(cfg.Sources) != 1 || len(cfg.Warnings) != 2 {
+		t.Fatalf("unexpected config: sources=%+v warnings=%q", cfg.Sources, cfg.Warnings)
+	}
+	if !strings.HasSuffix(cfg.Warnings[0], "config.yaml: newSetting: unknown key") || !strings.HasSuffix(cfg.Warnings[1], "config.yaml: sources[0].fromat: unknown key") {
+		t.Fatalf("unexpected warnings: %q", cfg.Warnings)
+	}
+}
+
+func TestLoadConfigLeavesPatternsUnexpanded(t *testing.T) {
+	t.Setenv("LOG_DIR", "/var/log/app")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+sources:
+  - name: app
+    path: ${LOG_DIR}/app.log
+    format: json
+    multiline: {startPattern: '^\$\{', continuationPattern: '^x${LOG_DIR}'}
+alerts:
+  - {name: env, pattern: 'missing \${LOG_DIR}|${LOG_DIR}', query: 'path:${LOG_DIR}'}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	src := cfg.Sources[0]
+	if src.Path != "/var/log/app/app.log" || src.Multiline.ContinuationPattern != "^x${LOG_DIR}" {
+		t.Fatalf("unexpected source: %+v", src)
+	}
+	if rule := cfg.Alerts[0]; rule.Pattern != `missing \${LOG_DIR}|${LOG_DIR}` || rule.Query != "path:/var/log/app" {
+		t.Fatalf("unexpected alert: %+v", rule)
+	}
+}
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+sources:
+  - {name: app, format: json}
+  - {name: app, path: b.log}
+  - {name: net, type: syslog, protocol: sctp}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := config.Load(path)
+	if err == nil {
+		t.Fatalf("expected validation errors")
+	}
+	want := []string{
+		"sources[0].path is required",
+		"sources[1].name \"app\" is already used by",
+		"sources[1].format is required",
+		"sources[2].address is required",
+		"sources[2].protocol must be udp or tcp",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Fatalf("expected %q in:\n%v", w, err)
+		}
+	}
+	if len(cfg.Sources) != 3 {
+		t.Fatalf("expected the parsed config alongside the errors, got %+v", cfg)
+	}
+}
+
+func TestLoadConfigExpandsEnvIntoNumbersAndBools(t *testing.T) {
+	t.Setenv("SINK_COMPRESS", "true")
+	t.Setenv("SINK_RETRIES", "2")
+	t.Setenv("SINK_FILES", "many")
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+
+	write(`{"sources":[{"name":"app","path":"a.log","format":"json"}],
+		"sinks":[{"type":"file","path":"out.log","maxSize":"${SINK_MAX_SIZE:-1000}","compress":"${SINK_COMPRESS}","retries":"${SINK_RETRIES}"}],
+		"alerts":[{"name":"errors","type":"threshold","pattern":"error","window":"1m","threshold":"${ERROR_THRESHOLD:-2.5}"}]}`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	sink := cfg.Sinks[0]
+	if sink.MaxSize != 1000 || !sink.Compress || sink.Retries == nil || *sink.Retries != 2 {
+		t.Fatalf("unexpected sink: %+v", sink)
+	}
+	if cfg.Alerts[0].Threshold != 2.5 {
+		t.Fatalf("expected threshold 2.5, got %v", cfg.Alerts[0].Threshold)
+	}
+
+	// Only references are converted; a literal string is still an error.
+	write(`{"sources":[],"sinks":[{"type":"file","path":"out.log","maxFiles":"${SINK_FILES}","maxSize":"1000"}]}`)
+	_, err = config.Load(path)
+	for _, want := range []string{"sinks[0].maxFiles: expected a number", "sinks[0].maxSize: expected a number"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
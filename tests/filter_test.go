@@ -59,3 +59,53 @@ func TestParseFieldAssignments(t *testing.T) {
 		t.Fatalf("expected error for invalid field")
 	}
 }
+
+func TestParseQuery(t *testing.T) {
+	event := parse.StructuredEvent{
+		SourceName: "nginx",
+		Severity:   "error",
+		Message:    "GET /api/users failed",
+		Raw:        "GET /api/users failed",
+		Fields: map[string]string{
+			"status": "503",
+			"path":   "/api/users",
+			"host":   "web-1",
+		},
+	}
+
+	cases := []struct {
+		query string
+		want  bool
+	}{
+		{`source:nginx AND status>=500 AND NOT path:/health*`, true},
+		{`source:NGINX status>=500`, true},
+		{`status<500 OR host:web-?`, true},
+		{`status>503`, false},
+		{`path:/api*`, true},
+		{`path:/api`, false},
+		{`"users failed"`, true},
+		{`timeout OR (severity:warn AND host:web-1)`, false},
+		{`NOT (source:app OR source:worker) AND failed`, true},
+		{`path~"^/api/v[0-9]+"`, false},
+		{`host~web-\d`, true},
+		{`region:*`, false},
+		{`region!=eu`, true},
+		{`message:users`, true},
+		{`path:"/api*"`, false},
+	}
+	for _, tc := range cases {
+		node, err := filter.ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.query, err)
+		}
+		if got := node.Matches(event); got != tc.want {
+			t.Fatalf("%q (%s): expected %v, got %v", tc.query, node, tc.want, got)
+		}
+	}
+
+	for _, bad := range []string{``, `(source:nginx`, `status>=abc`, `"open`, `path~"("`, `source:`} {
+		if _, err := filter.ParseQuery(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
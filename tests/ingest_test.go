@@ -1,10 +1,17 @@
 package tests
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"go-log-aggregator/internal/config"
 	"go-log-aggregator/internal/ingest"
 )
 
@@ -38,3 +45,281 @@ func TestReadAvailableHandlesPartialLine(t *testing.T) {
 		t.Fatalf("expected partial line to remain, got %q", partial)
 	}
 }
+
+func TestExpandPathsGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app-1.log",
+		"app-2.log",
+		"other.txt",
+		filepath.Join("pod-a", "app-3.log"),
+		filepath.Join("pod-a", "nested", "app-4.log"),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x\n"), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+
+	paths, err := ingest.ExpandPaths(filepath.Join(dir, "app-*.log"))
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 top-level matches, got %v", paths)
+	}
+
+	paths, err = ingest.ExpandPaths(filepath.Join(dir, "**", "*.log"))
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if len(paths) != 4 {
+		t.Fatalf("expected 4 recursive matches, got %v", paths)
+	}
+
+	paths, err = ingest.ExpandPaths(filepath.Join(dir, "pod-a"))
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected directory to expand to 2 files, got %v", paths)
+	}
+}
+
+func TestGlobTailerDiscoversNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan ingest.Event, 16)
+	source := config.Source{Name: "app", Path: filepath.Join(dir, "**", "app-*.log"), Format: "json"}
+	if err := ingest.StartTailer(ctx, source, nil, events, nil); err != nil {
+		t.Fatalf("start tailer: %v", err)
+	}
+
+	path := filepath.Join(dir, "app-1.log")
+	if err := os.WriteFile(path, []byte("hello\n"), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.SourceName != "app" || event.Line != "hello" {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+		if filepath.Base(event.SourcePath) != "app-1.log" {
+			t.Fatalf("expected concrete source path, got %s", event.SourcePath)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for event from discovered file")
+	}
+}
+
+func TestGlobTailerSharesOneWatcher(t *testing.T) {
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("needs /proc to count inotify instances")
+	}
+	inotify := func() int {
+		entries, _ := os.ReadDir("/proc/self/fd")
+		n := 0
+		for _, entry := range entries {
+			if target, err := os.Readlink(filepath.Join("/proc/self/fd", entry.Name())); err == nil && strings.Contains(target, "inotify") {
+				n++
+			}
+		}
+		return n
+	}
+
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("app-%02d.log", i)), nil, 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan ingest.Event, 16)
+	before := inotify()
+	source := config.Source{Name: "app", Path: filepath.Join(dir, "app-*.log"), Format: "plain"}
+	if err := ingest.StartTailer(ctx, source, nil, events, nil); err != nil {
+		t.Fatalf("start tailer: %v", err)
+	}
+	if used := inotify() - before; used != 1 {
+		t.Fatalf("expected one inotify instance for the glob, got %d", used)
+	}
+
+	// Writes still reach the right file's tailer.
+	for _, i := range []int{3, 17} {
+		path := filepath.Join(dir, fmt.Sprintf("app-%02d.log", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("line %d\n", i)), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		event := waitEvent(t, events)
+		if event.SourcePath != path || event.Line != fmt.Sprintf("line %d", i) {
+			t.Fatalf("unexpected event: %+v", event)
+		}
+	}
+}
+
+func TestTailerFollowsCopytruncate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan ingest.Event, 16)
+	source := config.Source{Name: "app", Path: path, Format: "json"}
+	if err := ingest.StartTailer(ctx, source, nil, events, nil); err != nil {
+		t.Fatalf("start tailer: %v", err)
+	}
+
+	appendLine(t, path, "before-rotate")
+	if event := waitEvent(t, events); event.Line != "before-rotate" {
+		t.Fatalf("expected before-rotate, got %q", event.Line)
+	}
+
+	if err := os.Truncate(path, 0); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	appendLine(t, path, "after")
+	if event := waitEvent(t, events); event.Line != "after" {
+		t.Fatalf("expected after, got %q", event.Line)
+	}
+}
+
+func TestTailerDrainsRenamedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan ingest.Event, 16)
+	source := config.Source{Name: "app", Path: path, Format: "json"}
+	if err := ingest.StartTailer(ctx, source, nil, events, nil); err != nil {
+		t.Fatalf("start tailer: %v", err)
+	}
+
+	appendLine(t, path, "first")
+	if event := waitEvent(t, events); event.Line != "first" {
+		t.Fatalf("expected first, got %q", event.Line)
+	}
+
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	appendLine(t, rotated, "late-write")
+	appendLine(t, path, "new-file")
+
+	for _, want := range []string{"late-write", "new-file"} {
+		event := waitEvent(t, events)
+		if event.Line != want {
+			t.Fatalf("expected %s, got %q", want, event.Line)
+		}
+	}
+}
+
+func TestSyslogListenerUDPAndTCP(t *testing.T) {
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("probe port: %v", err)
+	}
+	addr := probe.Addr().String()
+	_ = probe.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan ingest.Event, 16)
+	source := config.Source{Name: "net", Type: "syslog", Address: addr, Format: "syslog"}
+	if err := ingest.StartSource(ctx, source, nil, events, nil); err != nil {
+		t.Fatalf("start listener: %v", err)
+	}
+
+	udp, err := net.Dial("udp", addr)
+	if err != nil {
+		t.Fatalf("dial udp: %v", err)
+	}
+	defer udp.Close()
+	if _, err := udp.Write([]byte("<14>Jan 26 09:02:20 host1 app: over udp\n")); err != nil {
+		t.Fatalf("write udp: %v", err)
+	}
+	event := waitEvent(t, events)
+	if event.Line != "<14>Jan 26 09:02:20 host1 app: over udp" || event.Peer == "" {
+		t.Fatalf("unexpected udp event: %+v", event)
+	}
+
+	tcp, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial tcp: %v", err)
+	}
+	defer tcp.Close()
+	framed := "<14>1 - host1 app - - - octet\ncounted"
+	payload := fmt.Sprintf("<14>1 - host1 app - - - newline\n%d %s", len(framed), framed)
+	if _, err := tcp.Write([]byte(payload)); err != nil {
+		t.Fatalf("write tcp: %v", err)
+	}
+
+	for _, want := range []string{"<14>1 - host1 app - - - newline", framed} {
+		event := waitEvent(t, events)
+		if event.Line != want {
+			t.Fatalf("expected %q, got %q", want, event.Line)
+		}
+		if event.Peer != tcp.LocalAddr().String() {
+			t.Fatalf("expected peer %s, got %s", tcp.LocalAddr(), event.Peer)
+		}
+	}
+}
+
+func TestSyslogListenerRejectsOversizedFrames(t *testing.T) {
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("probe port: %v", err)
+	}
+	addr := probe.Addr().String()
+	_ = probe.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan ingest.Event, 16)
+	errs := make(chan error, 16)
+	source := config.Source{Name: "net", Type: "syslog", Address: addr, Format: "syslog"}
+	if err := ingest.StartSource(ctx, source, nil, events, errs); err != nil {
+		t.Fatalf("start listener: %v", err)
+	}
+
+	for _, tc := range []struct {
+		payload []byte
+		want    string
+	}{
+		{[]byte("123456789012345678901234567890"), "invalid octet count"},
+		{bytes.Repeat([]byte("a"), 2*1024*1024), "frame exceeds"},
+	} {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("dial tcp: %v", err)
+		}
+		// The listener hangs up mid-write, so the write error is expected.
+		_, _ = conn.Write(tc.payload)
+		select {
+		case err := <-errs:
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("expected %q error, got %v", tc.want, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %q error", tc.want)
+		}
+		_ = conn.Close()
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %d", len(events))
+	}
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/ingest"
+	"go-log-aggregator/internal/parse"
+)
+
+func TestAssemblerJoinsStackTrace(t *testing.T) {
+	assembler, err := ingest.NewAssembler([]config.Source{{
+		Name:   "app",
+		Path:   "app.log",
+		Format: "syslog",
+		Multiline: &config.Multiline{
+			StartPattern: `^[A-Z][a-z]{2} `,
+		},
+	}})
+	if err != nil {
+		t.Fatalf("new assembler: %v", err)
+	}
+
+	now := time.Now()
+	lines := []string{
+		"Jan 26 09:02:20 host1 myapp[1]: panic: runtime error",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"\t/app/main.go:12 +0x1d",
+		"Jan 26 09:02:21 host1 myapp[1]: restarted",
+	}
+	var done []ingest.Event
+	for i, line := range lines {
+		done = append(done, assembler.Add(ingest.Event{
+			SourceName: "app",
+			SourcePath: "/tmp/app.log",
+			Line:       line,
+			Offset:     int64(i + 1),
+			ReceivedAt: now,
+		})...)
+	}
+	if len(done) != 1 {
+		t.Fatalf("expected 1 completed event, got %d", len(done))
+	}
+	if strings.Count(done[0].Line, "\n") != 3 {
+		t.Fatalf("expected 4 joined lines, got %q", done[0].Line)
+	}
+	if done[0].Offset != 4 {
+		t.Fatalf("expected offset of last joined line, got %d", done[0].Offset)
+	}
+
+	if pending := assembler.Expire(now); len(pending) != 0 {
+		t.Fatalf("expected nothing expired before timeout")
+	}
+	pending := assembler.Expire(now.Add(2 * time.Second))
+	if len(pending) != 1 || pending[0].Line != lines[4] {
+		t.Fatalf("expected trailing event after timeout, got %+v", pending)
+	}
+
+	parsed, err := parse.ParseLine("syslog", done[0])
+	if err != nil {
+		t.Fatalf("parse multiline: %v", err)
+	}
+	if parsed.Raw != done[0].Line {
+		t.Fatalf("expected full text in raw")
+	}
+	if parsed.Fields["host"] != "host1" || !strings.Contains(parsed.Message, "main.go:12") {
+		t.Fatalf("unexpected parsed event: %+v", parsed)
+	}
+}
+
+func TestAssemblerContinuationAndMaxLines(t *testing.T) {
+	assembler, err := ingest.NewAssembler([]config.Source{{
+		Name:   "java",
+		Path:   "java.log",
+		Format: "json",
+		Multiline: &config.Multiline{
+			ContinuationPattern: `^\s+at |^Caused by:`,
+			MaxLines:            3,
+		},
+	}})
+	if err != nil {
+		t.Fatalf("new assembler: %v", err)
+	}
+
+	var done []ingest.Event
+	for _, line := range []string{
+		"Exception in thread main java.lang.IllegalStateException",
+		"    at com.example.A.run(A.java:10)",
+		"    at com.example.B.run(B.java:20)",
+		"    at com.example.C.run(C.java:30)",
+	} {
+		done = append(done, assembler.Add(ingest.Event{SourceName: "java", SourcePath: "/tmp/java.log", Line: line})...)
+	}
+	if len(done) != 1 || strings.Count(done[0].Line, "\n") != 2 {
+		t.Fatalf("expected max lines to flush a 3-line event, got %+v", done)
+	}
+	if rest := assembler.FlushAll(); len(rest) != 1 {
+		t.Fatalf("expected overflow line to remain pending, got %d", len(rest))
+	}
+
+	passthrough := assembler.Add(ingest.Event{SourceName: "other", Line: "single"})
+	if len(passthrough) != 1 {
+		t.Fatalf("expected sources without rules to pass through")
+	}
+
+	if _, err := ingest.NewAssembler([]config.Source{{Name: "bad", Multiline: &config.Multiline{}}}); err == nil {
+		t.Fatalf("expected error for multiline rule without patterns")
+	}
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/notify"
+	"go-log-aggregator/internal/parse"
+)
+
+func testMatch() alert.Match {
+	return alert.Match{
+		RuleName: "panic-detected",
+		Event: parse.StructuredEvent{
+			SourceName: "app",
+			Severity:   "critical",
+			Message:    "panic: nil map",
+		},
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New(config.Notifier{Type: "webhook", URL: server.URL, Backoff: "10ms"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMatch()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	var payload notify.Payload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Rule != "panic-detected" || payload.Message != "panic: nil map" {
+		t.Fatalf("unexpected payload %s: %v", body, err)
+	}
+	if bytes.Contains(body, []byte(`"timestamp"`)) {
+		t.Fatalf("expected no timestamp for an event without one: %s", body)
+	}
+
+	retries := 1
+	atomic.StoreInt32(&calls, -10)
+	notifier, _ = notify.New(config.Notifier{Type: "webhook", URL: server.URL, Backoff: "1ms", Retries: &retries})
+	if err := notifier.Notify(context.Background(), testMatch()); err == nil {
+		t.Fatalf("expected error after retries are exhausted")
+	}
+	if calls != -8 {
+		t.Fatalf("expected 2 attempts, got %d", calls+10)
+	}
+}
+
+func TestSlackTemplate(t *testing.T) {
+	received := make(chan map[string]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+		received <- msg
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New(config.Notifier{
+		Type:     "slack",
+		URL:      server.URL,
+		Channel:  "#oncall",
+		Template: `{{upper .Event.Severity}} {{.RuleName}} on {{.Event.SourceName}}: {{.Event.Message}}`,
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMatch()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	msg := <-received
+	if msg["text"] != "CRITICAL panic-detected on app: panic: nil map" || msg["channel"] != "#oncall" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if _, err := notify.New(config.Notifier{Type: "slack", URL: server.URL, Template: "{{.Nope"}); err == nil {
+		t.Fatalf("expected template error")
+	}
+	if _, err := notify.New(config.Notifier{Type: "pager"}); err == nil {
+		t.Fatalf("expected unknown type error")
+	}
+}
+
+func TestExecNotifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+	out := filepath.Join(t.TempDir(), "alert.txt")
+	notifier, err := notify.New(config.Notifier{
+		Type:    "exec",
+		Command: []string{"sh", "-c", `{ echo "$ALERT_RULE"; cat; } > "$0"`, out},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := notifier.Notify(context.Background(), testMatch()); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	lines := strings.SplitN(string(data), "\n", 2)
+	if lines[0] != "panic-detected" || !strings.Contains(lines[1], `"rule":"panic-detected"`) {
+		t.Fatalf("unexpected exec output: %q", data)
+	}
+}
+
+func TestDispatcherRoutesByRule(t *testing.T) {
+	received := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		received <- string(data)
+	}))
+	defer server.Close()
+
+	dispatcher, err := notify.NewDispatcher([]config.AlertRule{
+		{Name: "panic-detected", Pattern: "panic", Notify: []config.Notifier{{Type: "webhook", URL: server.URL, Template: `{"rule":"{{.RuleName}}"}`}}},
+		{Name: "quiet", Pattern: "x"},
+	})
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	if dispatcher.Send(alert.Match{RuleName: "quiet"}) {
+		t.Fatalf("expected rule without notifiers to be skipped")
+	}
+	if !dispatcher.Send(testMatch()) {
+		t.Fatalf("expected match to be queued")
+	}
+	select {
+	case body := <-received:
+		if body != `{"rule":"panic-detected"}` {
+			t.Fatalf("unexpected body: %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for notification")
+	}
+
+	if _, err := notify.NewDispatcher([]config.AlertRule{{Name: "bad", Notify: []config.Notifier{{Type: "webhook"}}}}); err == nil {
+		t.Fatalf("expected error for webhook without url")
+	}
+}
+
+func TestDispatcherDrainsQueueOnShutdown(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	dispatcher, err := notify.NewDispatcher([]config.AlertRule{
+		{Name: "panic-detected", Pattern: "panic", Notify: []config.Notifier{{Type: "webhook", URL: server.URL}}},
+	})
+	if err != nil {
+		t.Fatalf("new dispatcher: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if !dispatcher.Send(testMatch()) {
+			t.Fatalf("expected match %d to be queued", i)
+		}
+	}
+
+	// Cancelled before Run starts, so everything is still queued.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the drain")
+	}
+	if n := atomic.LoadInt32(&calls); n != 20 {
+		t.Fatalf("expected every queued notification delivered, got %d", n)
+	}
+}
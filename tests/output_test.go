@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/output"
+	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/sink"
+)
+
+func outputEvent() parse.StructuredEvent {
+	return parse.StructuredEvent{
+		SourceName: "nginx",
+		Format:     "nginx",
+		Severity:   "error",
+		Message:    `GET /api "items" 502`,
+		Raw:        "raw line",
+		Timestamp:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ReceivedAt: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC),
+		Fields:     map[string]string{"status": "502", "path": "/api items"},
+	}
+}
+
+func TestOutputFormatters(t *testing.T) {
+	tests := []struct {
+		mode string
+		opts output.Options
+		want string
+	}{
+		{
+			mode: "json",
+			want: `{"timestamp":"2024-05-01T12:00:00Z","received_at":"2024-05-01T12:00:01Z","severity":"error","message":"GET /api \"items\" 502","source":"nginx","format":"nginx","fields":{"path":"/api items","status":"502"},"raw":"raw line"}`,
+		},
+		{
+			mode: "logfmt",
+			want: `time=2024-05-01T12:00:00Z level=error source=nginx msg="GET /api \"items\" 502" path="/api items" status=502`,
+		},
+		{
+			mode: "template",
+			opts: output.Options{Template: `{{upper .Severity}} {{.SourceName}} {{index .Fields "status"}}`},
+			want: "ERROR nginx 502",
+		},
+	}
+	for _, tt := range tests {
+		formatter, err := output.New(tt.mode, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: new: %v", tt.mode, err)
+		}
+		got, err := formatter.Format(outputEvent())
+		if err != nil {
+			t.Fatalf("%s: format: %v", tt.mode, err)
+		}
+		if string(got) != tt.want {
+			t.Fatalf("%s:\n got %s\nwant %s", tt.mode, got, tt.want)
+		}
+	}
+
+	if formatter, err := output.New("none", output.Options{}); formatter != nil || err != nil {
+		t.Fatalf("expected no formatter for none, got %v %v", formatter, err)
+	}
+	if _, err := output.New("template", output.Options{}); err == nil {
+		t.Fatalf("expected error for template mode without template")
+	}
+	if _, err := output.New("xml", output.Options{}); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
+
+func TestTextOutputAlignsAndColors(t *testing.T) {
+	plain := &output.Text{}
+	event := outputEvent()
+	event.Fields = nil
+	long, _ := plain.Format(event)
+	event.SourceName = "db"
+	event.Severity = "info"
+	short, _ := plain.Format(event)
+
+	// Messages line up once a wider source has been seen.
+	if strings.Index(string(long), "GET") != strings.Index(string(short), "GET") {
+		t.Fatalf("expected aligned columns:\n%s\n%s", long, short)
+	}
+	if strings.Contains(string(long), "\x1b[") {
+		t.Fatalf("expected no colors without Color: %q", long)
+	}
+
+	colored, _ := (&output.Text{Color: true}).Format(outputEvent())
+	if !strings.Contains(string(colored), "\x1b[31mERROR") {
+		t.Fatalf("expected red severity: %q", colored)
+	}
+}
+
+func TestFileSinkUsesFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	file, err := sink.New(config.Sink{Type: "file", Path: path, Format: "logfmt"})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if err := file.Write(context.Background(), []parse.StructuredEvent{outputEvent()}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	file.Close()
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "time=2024-05-01T12:00:00Z level=error source=nginx ") {
+		t.Fatalf("unexpected file content %q", data)
+	}
+
+	if _, err := sink.New(config.Sink{Type: "file", Path: path, Format: "none"}); err == nil {
+		t.Fatalf("expected error for format none")
+	}
+}
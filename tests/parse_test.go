@@ -1,10 +1,13 @@
 package tests
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"go-log-aggregator/internal/config"
 	"go-log-aggregator/internal/ingest"
 	"go-log-aggregator/internal/parse"
 )
@@ -93,3 +96,204 @@ func TestParseUnsupported(t *testing.T) {
 		t.Fatalf("expected error for unsupported format")
 	}
 }
+
+func TestParseLogfmt(t *testing.T) {
+	line := `ts=2026-01-26T09:00:00Z level=warn msg="cache miss for \"user\"" user=42 path=/api/items empty=`
+	event := ingest.Event{SourceName: "api", SourcePath: "/tmp/api.log", Line: line}
+
+	parsed, err := parse.ParseLine("logfmt", event)
+	if err != nil {
+		t.Fatalf("parse logfmt: %v", err)
+	}
+
+	if parsed.Format != "logfmt" {
+		t.Fatalf("expected logfmt format, got %s", parsed.Format)
+	}
+	if parsed.Severity != "warn" {
+		t.Fatalf("expected warn severity, got %s", parsed.Severity)
+	}
+	if parsed.Message != `cache miss for "user"` {
+		t.Fatalf("unexpected message %q", parsed.Message)
+	}
+	if parsed.Fields["user"] != "42" || parsed.Fields["path"] != "/api/items" {
+		t.Fatalf("unexpected fields: %v", parsed.Fields)
+	}
+	if _, ok := parsed.Fields["empty"]; !ok {
+		t.Fatalf("expected empty field to be kept")
+	}
+	if _, ok := parsed.Fields["msg"]; ok {
+		t.Fatalf("expected msg to be lifted out of fields")
+	}
+	if parsed.Timestamp.IsZero() {
+		t.Fatalf("expected timestamp parsed")
+	}
+
+	if _, err := parse.ParseLine("logfmt", ingest.Event{Line: `msg="unterminated`}); err == nil {
+		t.Fatalf("expected error for unterminated quote")
+	}
+	if _, err := parse.ParseLine("logfmt", ingest.Event{Line: "plain text line"}); err == nil {
+		t.Fatalf("expected error for line without pairs")
+	}
+}
+
+func TestParseCustomFormat(t *testing.T) {
+	parser, err := parse.NewParser(config.Config{Formats: []config.Format{{
+		Name:            "billing",
+		Pattern:         `^(?P<ts>\S+ \S+) \[(?P<lvl>\w+)\] (?P<component>\w+): (?P<msg>.*)$`,
+		TimestampField:  "ts",
+		TimestampLayout: "2006-01-02 15:04:05",
+		SeverityField:   "lvl",
+		MessageField:    "msg",
+	}}})
+	if err != nil {
+		t.Fatalf("new parser: %v", err)
+	}
+
+	event := ingest.Event{SourceName: "billing", Line: "2026-01-26 09:00:00 [WARNING] invoices: retrying charge"}
+	parsed, err := parser.Parse("billing", event)
+	if err != nil {
+		t.Fatalf("parse custom: %v", err)
+	}
+	if parsed.Format != "billing" || parsed.Severity != "warn" || parsed.Message != "retrying charge" {
+		t.Fatalf("unexpected parsed event: %+v", parsed)
+	}
+	if parsed.Fields["component"] != "invoices" || len(parsed.Fields) != 1 {
+		t.Fatalf("unexpected fields: %v", parsed.Fields)
+	}
+	if !parsed.Timestamp.Equal(time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected timestamp: %v", parsed.Timestamp)
+	}
+	if _, err := parser.Parse("json", ingest.Event{Line: `{"msg":"ok"}`}); err != nil {
+		t.Fatalf("expected built-in formats to keep working: %v", err)
+	}
+
+	if _, err := parse.NewParser(config.Config{Formats: []config.Format{{Name: "json", Pattern: `(?P<a>.*)`}}}); err == nil {
+		t.Fatalf("expected error for reserved format name")
+	}
+	if _, err := parse.NewParser(config.Config{Formats: []config.Format{{Name: "bad", Pattern: `(?P<a>.*)`, MessageField: "missing"}}}); err == nil {
+		t.Fatalf("expected error for unknown group mapping")
+	}
+}
+
+func TestGrokBuiltinsCompile(t *testing.T) {
+	grok := parse.NewGrok()
+	for _, name := range grok.Names() {
+		if _, err := grok.Compile("%{" + name + "}"); err != nil {
+			t.Fatalf("builtin %s: %v", name, err)
+		}
+	}
+
+	re, err := grok.Compile(`%{IPORHOST:client} %{UUID:request_id} %{NUMBER:took:float}`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	matches := re.FindStringSubmatch("10.0.0.2 123e4567-e89b-12d3-a456-426614174000 0.25")
+	if matches == nil || matches[re.SubexpIndex("took")] != "0.25" {
+		t.Fatalf("unexpected matches: %v", matches)
+	}
+
+	if _, err := grok.Compile("%{NOPE}"); err == nil {
+		t.Fatalf("expected error for unknown pattern")
+	}
+}
+
+func TestParseGrokSource(t *testing.T) {
+	dir := t.TempDir()
+	patterns := filepath.Join(dir, "app.grok")
+	data := "# team patterns\nAPPLEVEL (?:DEBUG|INFO|WARN|ERROR)\nAPPLINE %{TIMESTAMP_ISO8601:timestamp} %{APPLEVEL:level} %{GREEDYDATA:message}\n"
+	if err := os.WriteFile(patterns, []byte(data), 0644); err != nil {
+		t.Fatalf("write patterns: %v", err)
+	}
+
+	parser, err := parse.NewParser(config.Config{
+		GrokPatternFiles: []string{patterns},
+		Sources: []config.Source{
+			{Name: "web", Path: "web.log", Format: "grok", Pattern: "%{COMBINEDAPACHELOG}"},
+			{Name: "app", Path: "app.log", Format: "grok", Pattern: "%{APPLINE}"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new parser: %v", err)
+	}
+
+	line := `10.0.0.2 - - [26/Jan/2026:09:02:00 +0000] "GET /api/items HTTP/1.1" 500 256 "-" "Go-http-client/1.1"`
+	parsed, err := parser.Parse("grok", ingest.Event{SourceName: "web", Line: line})
+	if err != nil {
+		t.Fatalf("parse grok: %v", err)
+	}
+	if parsed.Fields["clientip"] != "10.0.0.2" || parsed.Fields["response"] != "500" || parsed.Fields["verb"] != "GET" {
+		t.Fatalf("unexpected fields: %v", parsed.Fields)
+	}
+	if parsed.Timestamp.IsZero() {
+		t.Fatalf("expected HTTPDATE timestamp parsed")
+	}
+
+	parsed, err = parser.Parse("grok", ingest.Event{SourceName: "app", Line: "2026-01-26 09:00:00.123 ERROR db timeout"})
+	if err != nil {
+		t.Fatalf("parse grok: %v", err)
+	}
+	if parsed.Severity != "error" || parsed.Message != "db timeout" || parsed.Timestamp.IsZero() {
+		t.Fatalf("unexpected parsed event: %+v", parsed)
+	}
+
+	if _, err := parser.Parse("grok", ingest.Event{SourceName: "other", Line: "x"}); err == nil {
+		t.Fatalf("expected error for source without grok pattern")
+	}
+}
+
+func TestParseSyslogRFC3164WithPRI(t *testing.T) {
+	line := "<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8"
+	parsed, err := parse.ParseLine("syslog", ingest.Event{SourceName: "syslog", Line: line})
+	if err != nil {
+		t.Fatalf("parse syslog: %v", err)
+	}
+
+	if parsed.Severity != "critical" {
+		t.Fatalf("expected severity from PRI, got %s", parsed.Severity)
+	}
+	if parsed.Fields["facility"] != "auth" || parsed.Fields["host"] != "mymachine" {
+		t.Fatalf("unexpected fields: %v", parsed.Fields)
+	}
+}
+
+func TestParseSyslogRFC5424(t *testing.T) {
+	line := `<165>1 2026-01-26T09:02:20.003Z host1 evntslog 4321 ID47 [exampleSDID@32473 iut="3" eventSource="App \"x\""][meta class="high"] An application event`
+	parsed, err := parse.ParseLine("syslog", ingest.Event{SourceName: "syslog", Line: line})
+	if err != nil {
+		t.Fatalf("parse syslog: %v", err)
+	}
+
+	if parsed.Severity != "info" {
+		t.Fatalf("expected notice to map to info, got %s", parsed.Severity)
+	}
+	if parsed.Message != "An application event" {
+		t.Fatalf("unexpected message %q", parsed.Message)
+	}
+	want := map[string]string{
+		"facility":                      "local4",
+		"host":                          "host1",
+		"app_name":                      "evntslog",
+		"procid":                        "4321",
+		"msgid":                         "ID47",
+		"exampleSDID@32473.iut":         "3",
+		"exampleSDID@32473.eventSource": `App "x"`,
+		"meta.class":                    "high",
+	}
+	for key, value := range want {
+		if parsed.Fields[key] != value {
+			t.Fatalf("expected %s=%q, got %q", key, value, parsed.Fields[key])
+		}
+	}
+	if !parsed.Timestamp.Equal(time.Date(2026, 1, 26, 9, 2, 20, 3000000, time.UTC)) {
+		t.Fatalf("unexpected timestamp %v", parsed.Timestamp)
+	}
+
+	nilLine := "<11>1 - - - - - -"
+	parsed, err = parse.ParseLine("syslog", ingest.Event{Line: nilLine})
+	if err != nil {
+		t.Fatalf("parse nil values: %v", err)
+	}
+	if parsed.Severity != "error" || !parsed.Timestamp.IsZero() {
+		t.Fatalf("unexpected nil-value event: %+v", parsed)
+	}
+}
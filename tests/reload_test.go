@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/parse"
+)
+
+func TestDiffSources(t *testing.T) {
+	old := []config.Source{
+		{Name: "app", Path: "logs/app.log", Format: "json"},
+		{Name: "nginx", Path: "logs/access.log", Format: "nginx"},
+		{Name: "legacy", Path: "logs/legacy.log", Format: "plain"},
+	}
+	next := []config.Source{
+		{Name: "app", Path: "logs/app.log", Format: "json"},
+		{Name: "nginx", Path: "logs/access.log", Format: "nginx", Multiline: &config.Multiline{StartPattern: `^\d`}},
+		{Name: "syslog", Type: "syslog", Address: ":5514", Format: "syslog"},
+	}
+
+	diff := config.DiffSources(old, next)
+	if len(diff.Added) != 1 || diff.Added[0].Name != "syslog" {
+		t.Fatalf("expected syslog added, got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "legacy" {
+		t.Fatalf("expected legacy removed, got %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Name != "nginx" || diff.Changed[0].Multiline == nil {
+		t.Fatalf("expected new nginx definition, got %+v", diff.Changed)
+	}
+	if !config.DiffSources(old, old).Empty() {
+		t.Fatalf("expected no diff for identical sources")
+	}
+}
+
+func TestLoadConfigRejectsDuplicateSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `{"sources":[{"name":"app","path":"a.log","format":"json"},{"name":"app","path":"b.log","format":"json"}]}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := config.Load(path); err == nil {
+		t.Fatalf("expected error for duplicate source names")
+	}
+}
+
+func TestEvaluatorReloadKeepsUnchangedRules(t *testing.T) {
+	panics := config.AlertRule{Name: "panics", Type: "threshold", Pattern: "panic", Window: "1m", Threshold: 1}
+	timeouts := config.AlertRule{Name: "timeouts", Type: "threshold", Pattern: "timeout", Window: "1m", Threshold: 1}
+	eval, err := alert.NewEvaluator([]config.AlertRule{panics, timeouts})
+	if err != nil {
+		t.Fatalf("new evaluator: %v", err)
+	}
+
+	base := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		eval.Evaluate(parse.StructuredEvent{Message: "panic", ReceivedAt: base.Add(time.Duration(i) * time.Second)})
+		eval.Evaluate(parse.StructuredEvent{Message: "timeout", ReceivedAt: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	if _, _, err := eval.Reload([]config.AlertRule{{Name: "bad", Pattern: "("}}, base); err == nil {
+		t.Fatalf("expected error for invalid rule")
+	}
+
+	// Notify settings alone don't reset a rule; removing one resolves it.
+	panics.Notify = []config.Notifier{{Type: "webhook", URL: "http://example.invalid"}}
+	next, resolved, err := eval.Reload([]config.AlertRule{panics}, base.Add(5*time.Second))
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(resolved) != 1 || resolved[0].RuleName != "timeouts" || resolved[0].State != alert.StateResolved {
+		t.Fatalf("expected timeouts resolved, got %+v", resolved)
+	}
+
+	// Still firing: the window was carried over, so there is no new firing.
+	if matches := next.Evaluate(parse.StructuredEvent{Message: "panic", ReceivedAt: base.Add(6 * time.Second)}); len(matches) != 0 {
+		t.Fatalf("expected carried state, got %+v", matches)
+	}
+	matches := next.Tick(base.Add(2 * time.Minute))
+	if len(matches) != 1 || matches[0].RuleName != "panics" || matches[0].State != alert.StateResolved {
+		t.Fatalf("expected panics to resolve, got %+v", matches)
+	}
+
+	// The old evaluator is untouched by a reload.
+	if matches := eval.Tick(base.Add(10 * time.Second)); len(matches) != 0 {
+		t.Fatalf("expected old evaluator unchanged, got %+v", matches)
+	}
+}
+
+func TestWatchConfigSignalsChange(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"sources":[]}`), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan struct{}, 1)
+	if err := config.Watch(ctx, []string{path}, changed, nil); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatalf("write other: %v", err)
+	}
+	select {
+	case <-changed:
+		t.Fatalf("unexpected change for another file")
+	case <-time.After(time.Second):
+	}
+
+	// Saved the way editors do: a new file renamed over the old one.
+	tmp := filepath.Join(dir, ".config.json.tmp")
+	if err := os.WriteFile(tmp, []byte(`{"sources":[{"name":"app","path":"a.log","format":"json"}]}`), 0644); err != nil {
+		t.Fatalf("write tmp: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected change signal")
+	}
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/silence"
+	"go-log-aggregator/internal/web"
+)
+
+func TestSilenceMutesAndPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	store, err := silence.Open(path)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	now := time.Now()
+	created, err := store.Add(silence.Silence{
+		Matchers: []silence.Matcher{
+			{Name: "rule", Value: "api-500"},
+			{Name: "host", Value: "web-[12]", Regex: true},
+		},
+		EndsAt:    now.Add(time.Hour),
+		CreatedBy: "ops",
+		Comment:   "deploy",
+	}, now)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if created.ID == "" || created.State != silence.StateActive {
+		t.Fatalf("unexpected silence: %+v", created)
+	}
+
+	match := func(rule, host string) alert.Match {
+		return alert.Match{RuleName: rule, Event: parse.StructuredEvent{Fields: map[string]string{"host": host}}}
+	}
+	if id, muted := store.Muted(match("api-500", "web-1"), now); !muted || id != created.ID {
+		t.Fatalf("expected web-1 to be muted")
+	}
+	if _, muted := store.Muted(match("api-500", "web-3"), now); muted {
+		t.Fatalf("expected web-3 not to be muted")
+	}
+	if _, muted := store.Muted(match("panic", "web-1"), now); muted {
+		t.Fatalf("expected other rules not to be muted")
+	}
+	if _, muted := store.Muted(match("api-500", "web-2"), now.Add(2*time.Hour)); muted {
+		t.Fatalf("expected silence to end")
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+
+	reopened, err := silence.Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	list := reopened.List(now)
+	if len(list) != 1 || list[0].Suppressed != 1 || list[0].Comment != "deploy" {
+		t.Fatalf("unexpected silences after reopen: %+v", list)
+	}
+	if _, muted := reopened.Muted(match("api-500", "web-2"), now); !muted {
+		t.Fatalf("expected regex matcher to survive reopen")
+	}
+
+	if _, err := reopened.Expire(created.ID, now); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if _, muted := reopened.Muted(match("api-500", "web-1"), now.Add(time.Second)); muted {
+		t.Fatalf("expected expired silence not to mute")
+	}
+	if _, err := reopened.Add(silence.Silence{EndsAt: now.Add(time.Hour)}, now); err == nil {
+		t.Fatalf("expected error for silence without matchers")
+	}
+}
+
+func TestSilencesAPI(t *testing.T) {
+	store, _ := silence.Open("")
+	handler := web.NewHandler(nil, nil, nil, nil, store, nil)
+
+	body := `{"matchers":[{"name":"source","value":"nginx"}],"endsAt":"` + time.Now().Add(time.Hour).Format(time.RFC3339) + `","comment":"maintenance"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/silences", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", ct)
+	}
+	var created silence.Silence
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/silences", strings.NewReader(`{"matchers":[]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid silence, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/silences/"+created.ID, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on expire, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/silences/nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown silence, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/silences", nil))
+	var list []silence.Silence
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(list) != 1 || list[0].State != silence.StateExpired {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+}
+
+func TestSilenceAddRollsBackWhenSaveFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	store, err := silence.Open(filepath.Join(dir, "silences.json"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// A file where the directory should be makes every save fail.
+	if err := os.WriteFile(dir, nil, 0644); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+
+	now := time.Now()
+	_, err = store.Add(silence.Silence{
+		Matchers: []silence.Matcher{{Name: "rule", Value: "api-500"}},
+		EndsAt:   now.Add(time.Hour),
+	}, now)
+	if err == nil {
+		t.Fatalf("expected save error")
+	}
+	if list := store.List(now); len(list) != 0 {
+		t.Fatalf("expected the unsaved silence to be dropped, got %+v", list)
+	}
+	if _, muted := store.Muted(alert.Match{RuleName: "api-500"}, now); muted {
+		t.Fatalf("expected the unsaved silence not to mute")
+	}
+}
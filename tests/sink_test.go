@@ -0,0 +1,216 @@
+package tests
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/config"
+	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/sink"
+)
+
+func sinkEvent(source, message string) parse.StructuredEvent {
+	return parse.StructuredEvent{
+		SourceName: source,
+		Severity:   "error",
+		Message:    message,
+		Raw:        message,
+		Timestamp:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		ReceivedAt: time.Date(2024, 5, 1, 12, 0, 1, 0, time.UTC),
+	}
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.log")
+	file, err := sink.New(config.Sink{Type: "file", Path: path, MaxSize: 300, MaxFiles: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer file.Close()
+
+	for i := 0; i < 12; i++ {
+		if err := file.Write(context.Background(), []parse.StructuredEvent{sinkEvent("app", "request failed")}); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		// Rotated names carry a millisecond timestamp.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	rotated, _ := filepath.Glob(path + ".*.gz")
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 gzipped rotated files, got %v", rotated)
+	}
+	if leftovers, _ := filepath.Glob(path + ".*[0-9]"); len(leftovers) != 0 {
+		t.Fatalf("expected rotated files to be compressed, found %v", leftovers)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > 300 {
+		t.Fatalf("expected current file within maxSize: %v %v", info, err)
+	}
+
+	f, _ := os.Open(rotated[0])
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	line, _ := bufio.NewReader(gz).ReadString('\n')
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &doc); err != nil || doc["message"] != "request failed" {
+		t.Fatalf("unexpected rotated content %q: %v", line, err)
+	}
+}
+
+func TestElasticsearchSinkBatchesAndRetries(t *testing.T) {
+	var calls int32
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(data))
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	manager, err := sink.NewManager([]config.Sink{{
+		Type:          "elasticsearch",
+		URL:           server.URL,
+		BatchSize:     2,
+		FlushInterval: "1h",
+		Backoff:       "10ms",
+	}})
+	if err != nil {
+		t.Fatalf("new manager: %v", err)
+	}
+	manager.Start()
+	manager.Send(sinkEvent("app", "one"))
+	manager.Send(sinkEvent("app", "two"))
+	manager.Send(sinkEvent("app", "three"))
+	if err := manager.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected a full batch and a final flush, got %d bodies", len(bodies))
+	}
+	lines := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+	if len(lines) != 4 || lines[0] != `{"index":{"_index":"logs-2024.05.01"}}` || !strings.Contains(lines[1], `"message":"one"`) {
+		t.Fatalf("unexpected bulk body:\n%s", bodies[0])
+	}
+	if !strings.Contains(bodies[1], `"message":"three"`) {
+		t.Fatalf("expected remaining event to be flushed on close:\n%s", bodies[1])
+	}
+}
+
+func TestLokiSinkGroupsStreams(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		received <- data
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	loki, err := sink.New(config.Sink{Type: "loki", URL: server.URL, Labels: map[string]string{"job": "aggregator"}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	later := sinkEvent("app", "second")
+	later.Timestamp = later.Timestamp.Add(time.Second)
+	events := []parse.StructuredEvent{later, sinkEvent("nginx", "other"), sinkEvent("app", "first")}
+	if err := loki.Write(context.Background(), events); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	var payload struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(<-received, &payload); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(payload.Streams) != 2 {
+		t.Fatalf("expected 2 streams, got %+v", payload.Streams)
+	}
+	app := payload.Streams[0]
+	if app.Stream["source"] != "app" || app.Stream["job"] != "aggregator" || app.Stream["severity"] != "error" {
+		t.Fatalf("unexpected labels: %v", app.Stream)
+	}
+	if len(app.Values) != 2 || !strings.Contains(app.Values[0][1], "first") || app.Values[0][0] != "1714564800000000000" {
+		t.Fatalf("expected values oldest first: %v", app.Values)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer bad.Close()
+	loki, _ = sink.New(config.Sink{Type: "loki", URL: bad.URL})
+	if err := loki.Write(context.Background(), events); err == nil {
+		t.Fatalf("expected error for rejected push")
+	}
+}
+
+func TestSyslogSinkForwards(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer conn.Close()
+
+	forwarder, err := sink.New(config.Sink{Type: "syslog", Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	defer forwarder.Close()
+	if err := forwarder.Write(context.Background(), []parse.StructuredEvent{sinkEvent("api", "disk full")}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	msg := string(buf[:n])
+	// local0 (16) * 8 + error (3)
+	if !strings.HasPrefix(msg, "<131>1 2024-05-01T12:00:00Z ") || !strings.HasSuffix(msg, " api - - - disk full") {
+		t.Fatalf("unexpected syslog message %q", msg)
+	}
+
+	if _, err := sink.New(config.Sink{Type: "syslog", Address: "x:1", Protocol: "sctp"}); err == nil {
+		t.Fatalf("expected protocol error")
+	}
+}
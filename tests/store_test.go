@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/web"
+)
+
+func TestDiskStorePersistsAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	store, err := web.OpenDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	now := time.Now()
+	store.Add(web.Event{Timestamp: now.Add(-2 * time.Hour), Source: "app", Message: "too old"})
+	store.Add(web.Event{Timestamp: now, Source: "app", Message: "one"})
+	store.Add(web.Event{Timestamp: now, Source: "nginx", Message: "two"})
+	if err := store.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	store, err = web.OpenDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	events := store.Query(nil, time.Time{})
+	if len(events) != 2 || events[0].Message != "one" || events[1].Message != "two" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	events = store.Query([]string{"NGINX"}, now.Add(-time.Minute))
+	if len(events) != 1 || events[0].Message != "two" {
+		t.Fatalf("unexpected filtered events: %+v", events)
+	}
+
+	store.Add(web.Event{Timestamp: now, Source: "app", Message: "three"})
+	if events := store.Query([]string{"app"}, time.Time{}); len(events) != 2 {
+		t.Fatalf("expected append after reopen, got %+v", events)
+	}
+}
+
+func TestDiskStoreRecoversTornTail(t *testing.T) {
+	dir := t.TempDir()
+	store, err := web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "kept"})
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "torn"})
+	_ = store.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) != 1 {
+		t.Fatalf("expected one segment, got %v", segments)
+	}
+	info, err := os.Stat(segments[0])
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	// Simulate a crash part-way through writing the last record.
+	if err := os.Truncate(segments[0], info.Size()-5); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	store, err = web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	events := store.Query(nil, time.Time{})
+	if len(events) != 1 || events[0].Message != "kept" {
+		t.Fatalf("unexpected events after recovery: %+v", events)
+	}
+
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "after"})
+	events = store.Query(nil, time.Time{})
+	if len(events) != 2 || events[1].Message != "after" {
+		t.Fatalf("unexpected events after append: %+v", events)
+	}
+}
+
+func TestDiskStoreSkipsCorruptRecordsInOlderSegments(t *testing.T) {
+	dir := t.TempDir()
+	store, err := web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, message := range []string{"first", "corrupt", "third"} {
+		store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: message})
+	}
+	_ = store.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) != 1 {
+		t.Fatalf("expected one segment, got %v", segments)
+	}
+	old := filepath.Join(dir, "20000101T00-0000.seg")
+	if err := os.Rename(segments[0], old); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	// A newer segment makes the renamed one an older segment.
+	if store, err = web.OpenDiskStore(dir, 0, 0); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "newer"})
+	_ = store.Close()
+
+	data, err := os.ReadFile(old)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	// Flip a byte inside the second record's payload and leave a torn
+	// record at the end.
+	second := 8 + int(binary.BigEndian.Uint32(data[0:4]))
+	data[second+10] ^= 0xff
+	data = append(data, 0, 0, 0, 9, 1, 2)
+	if err := os.WriteFile(old, data, 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	store, err = web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "new"})
+
+	var messages []string
+	for _, event := range store.Query(nil, time.Time{}) {
+		messages = append(messages, event.Message)
+	}
+	if strings.Join(messages, ",") != "first,third,newer,new" {
+		t.Fatalf("unexpected events: %v", messages)
+	}
+	if info, err := os.Stat(old); err != nil || info.Size() != int64(len(data)) {
+		t.Fatalf("expected the older segment untouched, got %v", info)
+	}
+}
+
+func TestDiskStorePrunesEmptySegmentsByAge(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "20000101T00-0000.seg")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	store, err := web.OpenDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer store.Close()
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Fatalf("expected empty segment pruned, got %v", err)
+	}
+}
+
+func TestDiskStoreDropsOversizedRecords(t *testing.T) {
+	dir := t.TempDir()
+	store, err := web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "before"})
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: strings.Repeat("x", 17*1024*1024)})
+	store.Add(web.Event{Timestamp: time.Now(), Source: "app", Message: "after"})
+	_ = store.Close()
+
+	store, err = web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	var messages []string
+	for _, event := range store.Query(nil, time.Time{}) {
+		messages = append(messages, event.Message)
+	}
+	if strings.Join(messages, ",") != "before,after" {
+		t.Fatalf("unexpected events: %v", messages)
+	}
+}
+
+func TestDiskStoreSizeRetention(t *testing.T) {
+	dir := t.TempDir()
+	// Leave behind an older segment, as a previous run would.
+	store, err := web.OpenDiskStore(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		store.Add(web.Event{Timestamp: time.Now(), Source: "old", Message: "filler"})
+	}
+	_ = store.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if len(segments) != 1 {
+		t.Fatalf("expected one segment, got %v", segments)
+	}
+	if err := os.Rename(segments[0], filepath.Join(dir, "20000101T00-0000.seg")); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	store, err = web.OpenDiskStore(dir, 0, 1024)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	store.Add(web.Event{Timestamp: time.Now(), Source: "new", Message: "fresh"})
+
+	if store.Size() > 1024 {
+		t.Fatalf("expected size under limit, got %d", store.Size())
+	}
+	events := store.Query(nil, time.Time{})
+	if len(events) != 1 || events[0].Source != "new" {
+		t.Fatalf("unexpected events after retention: %+v", events)
+	}
+}
@@ -0,0 +1,370 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-log-aggregator/internal/alert"
+	"go-log-aggregator/internal/filter"
+	"go-log-aggregator/internal/parse"
+	"go-log-aggregator/internal/web"
+)
+
+func TestIngestEndpoint(t *testing.T) {
+	var received []string
+	ingest := func(ctx context.Context, source, peer string, lines []string) error {
+		if source != "ci" {
+			return fmt.Errorf("%w: %s", web.ErrUnknownSource, source)
+		}
+		received = append(received, lines...)
+		return nil
+	}
+	handler := web.NewHandler(nil, nil, nil, ingest, nil, nil)
+
+	body := "{\"level\":\"info\",\"msg\":\"build started\"}\n\"plain line\"\nnot json\n42\n"
+	result := postIngest(t, handler, "/api/ingest?source=ci", strings.NewReader(body), "", http.StatusOK)
+	if result.Accepted != 2 || result.Rejected != 2 || len(result.Errors) != 2 {
+		t.Fatalf("unexpected ndjson result: %+v", result)
+	}
+	if received[0] != `{"level":"info","msg":"build started"}` || received[1] != "plain line" {
+		t.Fatalf("unexpected lines: %q", received)
+	}
+
+	var gz bytes.Buffer
+	writer := gzip.NewWriter(&gz)
+	_, _ = writer.Write([]byte(`[{"msg":"a"}, "b", null]`))
+	_ = writer.Close()
+	result = postIngest(t, handler, "/api/ingest?source=ci", &gz, "gzip", http.StatusOK)
+	if result.Accepted != 2 || result.Rejected != 1 {
+		t.Fatalf("unexpected array result: %+v", result)
+	}
+
+	postIngest(t, handler, "/api/ingest?source=other", strings.NewReader(`"x"`), "", http.StatusNotFound)
+	postIngest(t, handler, "/api/ingest", strings.NewReader(`"x"`), "", http.StatusBadRequest)
+	postIngest(t, handler, "/api/ingest?source=ci", strings.NewReader(`[{"msg":`), "", http.StatusBadRequest)
+
+	// Small on the wire, over the limit once decompressed.
+	gz.Reset()
+	writer = gzip.NewWriter(&gz)
+	line := "\"" + strings.Repeat("x", 64*1024) + "\"\n"
+	_, _ = writer.Write([]byte(strings.Repeat(line, 600)))
+	_ = writer.Close()
+	received = nil
+	postIngest(t, handler, "/api/ingest?source=ci", &gz, "gzip", http.StatusRequestEntityTooLarge)
+	if len(received) != 0 {
+		t.Fatalf("expected nothing ingested from an oversized body, got %d lines", len(received))
+	}
+}
+
+func postIngest(t *testing.T, handler http.Handler, url string, body io.Reader, encoding string, status int) web.IngestResult {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, url, body)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != status {
+		t.Fatalf("POST %s: expected status %d, got %d (%s)", url, status, rec.Code, rec.Body.String())
+	}
+
+	var result web.IngestResult
+	if status == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+			t.Fatalf("decode result: %v", err)
+		}
+	}
+	return result
+}
+
+func TestEventsQueryParameter(t *testing.T) {
+	store := web.NewStore(time.Hour, 100)
+	store.Add(web.Event{Timestamp: time.Now(), Source: "nginx", Message: "ok", Fields: map[string]string{"status": "200"}})
+	store.Add(web.Event{Timestamp: time.Now(), Source: "nginx", Message: "boom", Fields: map[string]string{"status": "502"}})
+	handler := web.NewHandler(nil, store, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/events?query="+url.QueryEscape("status>=500"), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var events []web.Event
+	if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+		t.Fatalf("decode: %v (%s)", err, rec.Body.String())
+	}
+	if len(events) != 1 || events[0].Message != "boom" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/events?query="+url.QueryEscape("(status"), nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid query, got %d", rec.Code)
+	}
+}
+
+func TestEventsKeepsNewestWithinLimit(t *testing.T) {
+	store := web.NewStore(0, 100)
+	base := time.Now().Add(-time.Minute)
+	for i := 0; i < 10; i++ {
+		store.Add(web.Event{Timestamp: base.Add(time.Duration(i) * time.Second), Source: "app", Message: fmt.Sprintf("e%d", i), Fields: map[string]string{"n": fmt.Sprint(i)}})
+	}
+	handler := web.NewHandler(nil, store, nil, nil, nil, nil)
+
+	get := func(target string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var events []web.Event
+		if err := json.Unmarshal(rec.Body.Bytes(), &events); err != nil {
+			t.Fatalf("decode %s: %v (%s)", target, err, rec.Body.String())
+		}
+		out := make([]string, 0, len(events))
+		for _, event := range events {
+			out = append(out, event.Message)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := get("/api/events?limit=3"); got != "e7,e8,e9" {
+		t.Fatalf("unexpected newest events: %s", got)
+	}
+	if got := get("/api/events?limit=2&query=" + url.QueryEscape("n<5")); got != "e3,e4" {
+		t.Fatalf("unexpected filtered events: %s", got)
+	}
+	if got := get("/api/events"); len(strings.Split(got, ",")) != 10 {
+		t.Fatalf("expected every event under the cap, got %s", got)
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	store := web.NewStore(0, 100)
+	base := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		store.Add(web.Event{Timestamp: base.Add(time.Duration(i) * time.Second), Source: "app", Message: fmt.Sprintf("e%d", i), Fields: map[string]string{"n": fmt.Sprint(i)}})
+	}
+	// Same timestamp as e4, stored later.
+	store.Add(web.Event{Timestamp: base.Add(4 * time.Second), Source: "app", Message: "e5", Fields: map[string]string{"n": "5"}})
+	store.Add(web.Event{Timestamp: base, Source: "other", Message: "skip"})
+	handler := web.NewHandler(nil, store, nil, nil, nil, nil)
+
+	search := func(params url.Values) web.SearchResult {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/search?"+params.Encode(), nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %v: status %d: %s", params, rec.Code, rec.Body.String())
+		}
+		var result web.SearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return result
+	}
+	messages := func(events []web.Event) string {
+		out := make([]string, 0, len(events))
+		for _, event := range events {
+			out = append(out, event.Message)
+		}
+		return strings.Join(out, ",")
+	}
+
+	params := url.Values{"sources": {"app"}, "from": {"1h"}, "limit": {"4"}}
+	var pages []string
+	for {
+		result := search(params)
+		if result.Total != 6 {
+			t.Fatalf("expected total 6, got %d", result.Total)
+		}
+		pages = append(pages, messages(result.Events))
+		if result.Next == "" {
+			break
+		}
+		params.Set("cursor", result.Next)
+	}
+	if strings.Join(pages, "|") != "e5,e4,e3,e2|e1,e0" {
+		t.Fatalf("unexpected desc pages: %q", pages)
+	}
+
+	params = url.Values{"query": {"n>=2"}, "order": {"asc"}, "limit": {"2"}, "to": {base.Add(3 * time.Second).Format(time.RFC3339)}}
+	result := search(params)
+	if messages(result.Events) != "e2,e3" || result.Next != "" || result.Total != 2 {
+		t.Fatalf("unexpected asc result: %+v", result)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search?order=asc&cursor="+url.QueryEscape(search(url.Values{"limit": {"1"}}).Next), nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for cursor with other order, got %d", rec.Code)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	store := web.NewStore(0, 100)
+	base := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	add := func(offset time.Duration, source, severity, path, bytes string) {
+		store.Add(web.Event{
+			Timestamp: base.Add(offset),
+			Source:    source,
+			Severity:  severity,
+			Fields:    map[string]string{"path": path, "bytes": bytes},
+		})
+	}
+	add(10*time.Second, "nginx", "info", "/", "100")
+	add(20*time.Second, "nginx", "error", "/api", "200")
+	add(70*time.Second, "nginx", "error", "/api", "300")
+	add(80*time.Second, "app", "info", "/", "-")
+	add(150*time.Second, "nginx", "info", "/api", "400")
+
+	result, err := web.Aggregate(store, web.AggregateRequest{
+		From:     base,
+		To:       base.Add(3 * time.Minute),
+		Interval: time.Minute,
+		GroupBy:  "severity",
+		Top:      []string{"path"},
+		Stats:    []string{"bytes"},
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+
+	if result.Total != 5 || len(result.Buckets) != 4 {
+		t.Fatalf("unexpected totals: %+v", result)
+	}
+	if result.Buckets[0].Count != 2 || result.Buckets[1].Groups["error"] != 1 || result.Buckets[1].Groups["info"] != 1 || result.Buckets[3].Count != 0 {
+		t.Fatalf("unexpected buckets: %+v", result.Buckets)
+	}
+	if strings.Join(result.Groups, ",") != "info,error" {
+		t.Fatalf("unexpected groups: %v", result.Groups)
+	}
+	if top := result.Top["path"]; len(top) != 2 || top[0].Value != "/api" || top[0].Count != 3 {
+		t.Fatalf("unexpected top values: %+v", top)
+	}
+	stats := result.Stats["bytes"]
+	if stats.Count != 4 || stats.Min != 100 || stats.Max != 400 || stats.Avg != 250 || stats.P50 != 250 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	result, err = web.Aggregate(store, web.AggregateRequest{
+		From:    base,
+		To:      base.Add(3 * time.Minute),
+		GroupBy: "source",
+		Query:   mustQuery(t, "severity:error"),
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if result.Total != 2 || result.Interval != "5s" || strings.Join(result.Groups, ",") != "nginx" {
+		t.Fatalf("unexpected filtered result: total=%d interval=%s groups=%v", result.Total, result.Interval, result.Groups)
+	}
+
+	handler := web.NewHandler(nil, store, nil, nil, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/aggregate?from=1h&interval=1ms", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many buckets, got %d", rec.Code)
+	}
+}
+
+func TestAggregateBoundsTopAndStats(t *testing.T) {
+	store := web.NewStore(0, 50000)
+	base := time.Date(2026, 1, 26, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 30000; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		if i%3 == 0 {
+			user = "bot"
+		}
+		store.Add(web.Event{
+			Timestamp: base.Add(time.Duration(i) * time.Millisecond),
+			Source:    "app",
+			Fields:    map[string]string{"user": user, "ms": strconv.Itoa(i%1000 + 1)},
+		})
+	}
+
+	result, err := web.Aggregate(store, web.AggregateRequest{
+		From:  base,
+		To:    base.Add(time.Minute),
+		Top:   []string{"user"},
+		TopN:  1,
+		Stats: []string{"ms"},
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if top := result.Top["user"]; len(top) != 1 || top[0].Value != "bot" || top[0].Count < 10000 {
+		t.Fatalf("unexpected top values: %+v", top)
+	}
+	stats := result.Stats["ms"]
+	if stats.Count != 30000 || stats.Min != 1 || stats.Max != 1000 || stats.Avg != 500.5 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	if stats.P50 < 400 || stats.P50 > 600 || stats.P99 < 950 {
+		t.Fatalf("percentiles out of range: %+v", stats)
+	}
+}
+
+func mustQuery(t *testing.T, query string) filter.Node {
+	t.Helper()
+	node, err := filter.ParseQuery(query)
+	if err != nil {
+		t.Fatalf("parse query %q: %v", query, err)
+	}
+	return node
+}
+
+func TestAlertsEndpoint(t *testing.T) {
+	history := web.NewAlertHistory(3)
+	now := time.Now()
+	event := parse.StructuredEvent{SourceName: "nginx", Message: "GET / 502", Raw: "GET / 502"}
+
+	history.Record(alert.Match{RuleName: "panic", Event: event, Count: 1}, "", now)
+	history.Record(alert.Match{RuleName: "5xx", State: alert.StateFiring, Group: "web-1", Event: event, Window: time.Minute}, "", now)
+	history.Record(alert.Match{RuleName: "5xx", State: alert.StateFiring, Group: "web-2", Event: event, Window: time.Minute}, "s1", now)
+	history.Record(alert.Match{RuleName: "5xx", State: alert.StateResolved, Group: "web-1", Window: time.Minute}, "", now)
+
+	handler := web.NewHandler(nil, nil, nil, nil, nil, history)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/alerts", nil))
+	var snapshot web.AlertsSnapshot
+	if err := json.Unmarshal(rec.Body.Bytes(), &snapshot); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if len(snapshot.Firing) != 1 || snapshot.Firing[0].Group != "web-2" || snapshot.Firing[0].SilencedBy != "s1" {
+		t.Fatalf("unexpected firing alerts: %+v", snapshot.Firing)
+	}
+	if len(snapshot.History) != 3 || snapshot.History[0].State != alert.StateResolved || snapshot.History[2].Group != "web-1" {
+		t.Fatalf("expected the 3 newest records, newest first: %+v", snapshot.History)
+	}
+	if len(snapshot.History[2].Events) != 1 || snapshot.History[2].Events[0].Source != "nginx" {
+		t.Fatalf("expected triggering event to be recorded: %+v", snapshot.History[2])
+	}
+	if len(snapshot.Rules) != 2 {
+		t.Fatalf("expected stats for 2 rules, got %+v", snapshot.Rules)
+	}
+	stats := snapshot.Rules[0]
+	if stats.Rule != "5xx" || stats.Alerts != 3 || stats.Fired != 2 || stats.Resolved != 1 || stats.Silenced != 1 || stats.Firing != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/alerts?rule=panic&limit=5", nil))
+	snapshot = web.AlertsSnapshot{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &snapshot)
+	// The panic record was evicted from history but still counts.
+	if len(snapshot.History) != 0 || len(snapshot.Firing) != 0 || len(snapshot.Rules) != 1 || snapshot.Rules[0].Alerts != 1 {
+		t.Fatalf("unexpected snapshot for rule filter: %+v", snapshot)
+	}
+}